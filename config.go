@@ -15,16 +15,25 @@
 package fastecho
 
 import (
+	"context"
+	"os"
+	"time"
+
 	"github.com/ingka-group/fastecho/env"
 	"github.com/ingka-group/fastecho/router"
 
 	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
 	"gorm.io/gorm"
 )
 
 // Config serves as input configuration for fastecho.
 type Config struct {
-	ExtraEnvs           env.Map
+	ExtraEnvs env.Map
+	// ExtraConfig is a pointer to a struct with env tags, which is populated with the values of its variables
+	// along with ExtraEnvs. See env.Bind. It is populated once at startup, i.e. it is not updated when the
+	// variables are reloaded by Opts.Env.Watch.
+	ExtraConfig         any
 	ValidationRegistrar func(v *router.Validator) error
 	Routes              func(e *echo.Echo, r *router.Router) error
 	// ContextProps would be shared across all requests in the service
@@ -32,35 +41,107 @@ type Config struct {
 	Opts         Opts
 	Plugins      []Plugin
 	EchoFn       func(e *echo.Echo) error
+	// PipelineFn allows disabling, reordering, replacing or inserting middlewares of the server
+	PipelineFn func(p *Pipeline) error
+	// OnStart hooks are invoked in order before the server starts accepting requests.
+	OnStart []Hook
+	// OnStop hooks are invoked in reverse order once the server has stopped accepting requests. The OnStop hook
+	// at a given position is only invoked if the OnStart hook at the same position, if any, succeeded.
+	OnStop []Hook
+	// SkipRoutes are skipped by the built-in middlewares. They are matched exactly against the route of the request.
+	SkipRoutes []string
+	// DBOptions select the database and the migrations managed by the `migrate` subcommand of Run.
+	DBOptions []DBOption
 }
 
+// Hook is a callback invoked during the lifecycle of the server.
+type Hook func(ctx context.Context) error
+
 // Opts define configuration options for fastecho.
 type Opts struct {
 	Metrics      MetricsOpts
 	Tracing      TracingOpts
 	HealthChecks HealthChecksOpts
+	Shutdown     ShutdownOpts
+	Env          EnvOpts
+	Transactions TransactionOpts
 }
 
 // MetricsOpts define configuration options for metrics.
 type MetricsOpts struct {
 	Skip bool
+	// Registry to which the metrics are registered, and which is served by the metrics endpoint, e.g. a registry
+	// per server to run several servers in one process. Defaults to the default registerer and gatherer of
+	// Prometheus.
+	Registry *prometheus.Registry
 }
 
 // TracingOpts define configuration options for tracing.
 type TracingOpts struct {
 	Skip        bool
 	ServiceName string
+	// Isolated keeps the tracer provider and the propagator of the server out of the OpenTelemetry globals,
+	// e.g. to run several servers in one process. By default, they are registered as the globals.
+	Isolated bool
 }
 
 // HealthChecksOpts define configuration options for health checks.
 type HealthChecksOpts struct {
 	Skip bool
 	DB   *gorm.DB
+	// Databases are checked in addition to DB, including their replicas. The databases created once the server
+	// is set up are checked too.
+	Databases *Databases
+	// DrainDelay is the time during which the readiness check fails but live traffic is still served
+	// once the shutdown begins. It gives load balancers time to stop routing requests to the service.
+	DrainDelay time.Duration
+}
+
+// TransactionOpts define configuration options for the transactions of the requests.
+type TransactionOpts struct {
+	// DB enables a transaction per request, which is committed when the response is successful and rolled
+	// back otherwise. Handlers get it with ServiceContext.Tx or ServiceContext.DB, and services with context.GetTx.
+	DB *gorm.DB
+	// MutatingOnly restricts the transactions to the POST, PUT, PATCH and DELETE requests.
+	MutatingOnly bool
+}
+
+// EnvOpts define configuration options for reading the environment variables.
+type EnvOpts struct {
+	// Sources from which the variables are read, in order of precedence. When empty, the variables are
+	// read from the environment of the process, complemented by the DotEnv files.
+	Sources []env.Source
+	// DotEnv files complementing the environment of the process when no Sources are given. Defaults to
+	// `.env`, `.env.<ENV_TYPE>` and `.env.local` in the working directory.
+	DotEnv env.DotEnvFiles
+	// ExposeConfig registers the `/config` endpoint, which returns the effective configuration with secrets
+	// redacted and the source of each value. It is only hosted by the admin server, i.e. ADMIN_PORT is required.
+	ExposeConfig bool
+	// Watch enables reloading the variables at runtime when the files of the sources change or the process
+	// receives SIGHUP. It is called with the watcher, e.g. to subscribe to the variables that can change.
+	Watch func(w *env.Watcher) error
+}
+
+// ShutdownOpts define configuration options for the graceful shutdown of the server.
+type ShutdownOpts struct {
+	// Timeout is the time given to in-flight requests to complete. When zero, the value of the
+	// SHUTDOWN_TIMEOUT environment variable is used.
+	Timeout time.Duration
+	// Signals that trigger the shutdown. Defaults to SIGINT and SIGTERM.
+	Signals []os.Signal
+	// Stop triggers the shutdown programmatically when it is closed or receives a value.
+	Stop <-chan struct{}
 }
 
 type Plugin struct {
 	ValidationRegistrar func(v *router.Validator) error
 	Routes              func(e *echo.Echo, r *router.Router) error
+	// OnStart hooks are invoked after the OnStart hooks of the Config and of the plugins registered before.
+	OnStart []Hook
+	// OnStop hooks are invoked before the OnStop hooks of the plugins registered before and of the Config.
+	OnStop []Hook
+	// SkipRoutes are skipped by the built-in middlewares. They are matched exactly against the route of the request.
+	SkipRoutes []string
 }
 
 func (c *Config) Use(p Plugin) {
@@ -15,9 +15,12 @@
 package context
 
 import (
+	gocontext "context"
+
 	"github.com/labstack/echo/v4"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 // ServiceContext contains the echo.Context and custom properties vital for a microservice.
@@ -29,6 +32,8 @@ type ServiceContext[T any] struct {
 	Props T
 	// RequestProps values are unique to each requests
 	RequestProps map[string]interface{}
+	// ClientSubject is the subject of the verified client certificate when the server requires mTLS
+	ClientSubject string
 }
 
 // BindValidate binds the data to the given interface and validates the input given using validator/10.
@@ -44,6 +49,36 @@ func (c *ServiceContext[T]) BindValidate(i interface{}) error {
 	return nil
 }
 
+// DB returns the transaction of the request or, if there is none, the database bound to the context of the
+// request, so that its queries are traced under the span of the request.
+func (c *ServiceContext[T]) DB(db *gorm.DB) *gorm.DB {
+	return GetTx(c.Request().Context(), db)
+}
+
+// Tx returns the transaction of the request, or nil if the request doesn't run in a transaction.
+func (c *ServiceContext[T]) Tx() *gorm.DB {
+	tx, _ := c.Request().Context().Value(txKey{}).(*gorm.DB)
+	return tx
+}
+
+// txKey is the key of the transaction of a request in its context.
+type txKey struct{}
+
+// WithTx returns a copy of the context which carries the transaction.
+func WithTx(ctx gocontext.Context, tx *gorm.DB) gocontext.Context {
+	return gocontext.WithValue(ctx, txKey{}, tx)
+}
+
+// GetTx returns the transaction carried by the context or, if there is none, the database bound to the
+// context. Services given the context of a request run their queries in its transaction this way.
+func GetTx(ctx gocontext.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx
+	}
+
+	return db.WithContext(ctx)
+}
+
 // GetServiceContext returns the ServiceContext from echo.Context.
 func GetServiceContext[T any](ctx echo.Context) *ServiceContext[T] {
 	return ctx.(*ServiceContext[T])
@@ -74,6 +109,11 @@ func ServiceContextMiddleware[T any](logger *zap.Logger, tracer *trace.Tracer, p
 				sctx.Tracer = tracer
 			}
 
+			// Add the subject of the verified client certificate to the service context
+			if req.TLS != nil && len(req.TLS.VerifiedChains) > 0 && len(req.TLS.VerifiedChains[0]) > 0 {
+				sctx.ClientSubject = req.TLS.VerifiedChains[0][0].Subject.String()
+			}
+
 			return next(sctx)
 		}
 	}
@@ -19,19 +19,31 @@
 	"database/sql"
 	"errors"
 	"fmt"
-	"log"
+	"io/fs"
+	"maps"
+	"net"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	_ "github.com/jackc/pgx/v5/stdlib" // registers the `pgx` driver
 	"github.com/pressly/goose/v3"
-	"gorm.io/driver/postgres"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
+	"gorm.io/plugin/dbresolver"
 
+	"github.com/ingka-group/fastecho/echozap"
 	"github.com/ingka-group/fastecho/env"
+	"github.com/ingka-group/fastecho/gormotel"
+	"github.com/ingka-group/fastecho/gormzap"
+	"github.com/ingka-group/fastecho/stringutils"
 )
 
 const (
+	dbDriverName      = "DB_DRIVER"
 	dbName            = "DB_NAME"
 	dbHostname        = "DB_HOST"
 	dbPort            = "DB_PORT"
@@ -41,151 +53,609 @@
 	dbMaxOpenConn     = "DB_MAX_OPEN_CONNECTIONS"
 	dbMaxIdleConn     = "DB_MAX_IDLE_CONNECTIONS"
 	dbMaxConnLifeTime = "DB_CONNECTION_MAX_LIFETIME"
+	dbReplicaHosts    = "DB_REPLICA_HOSTS"
+	dbAutoMigrate     = "DB_AUTO_MIGRATE"
+	dbSSLRootCert     = "DB_SSL_ROOT_CERT"
+	dbSSLCert         = "DB_SSL_CERT"
+	dbSSLKey          = "DB_SSL_KEY"
+	dbAppName         = "DB_APPLICATION_NAME"
+	dbSearchPath      = "DB_SEARCH_PATH"
+	dbStmtTimeout     = "DB_STATEMENT_TIMEOUT"
+	dbConnectTimeout  = "DB_CONNECT_TIMEOUT"
+	dbConnectRetries  = "DB_CONNECT_RETRIES"
+	dbSlowThreshold   = "DB_SLOW_QUERY_THRESHOLD"
+
+	// connectBackoff is the delay before the first retry to connect to the database, doubled for every retry
+	// up to maxConnectBackoff.
+	connectBackoff    = 500 * time.Millisecond
+	maxConnectBackoff = 10 * time.Second
+
+	// sslModeEnable is accepted for backwards compatibility, although it isn't a libpq mode. It stands for `require`.
+	sslModeEnable = "enable"
+
+	// defaultMigrationsDir is the directory of the migrations, relative to the working directory by default.
+	defaultMigrationsDir = "db/migrations"
 )
 
-var (
-	dbEnvs = env.Map{
+// newDBEnvs returns the environment variables of the database connection.
+func newDBEnvs() env.Map {
+	return env.Map{
+		dbDriverName: {
+			Description:  "Driver of the database, `postgres` or one registered by importing its package, e.g. a pure-Go `sqlite` from `fastecho/sqlite` for tests and local runs",
+			DefaultValue: DriverPostgres,
+		},
 		dbHostname: {
+			Description:  "Host of the database, or the directory of its Unix socket, e.g. `/var/run/postgresql`",
 			DefaultValue: "localhost",
 		},
 		dbPort: {
+			Description:  "Port of the database",
 			DefaultValue: "5432",
 			IsInteger:    true,
 		},
-		dbName:     {},
-		dbUsername: {},
-		dbPassword: {},
+		dbName: {
+			Description: "Name of the database or, with the `sqlite` driver, the path of its file or `:memory:`",
+		},
+		dbUsername: {
+			Description: "User the service connects to the database with, required by the `postgres` driver",
+			Optional:    true,
+		},
+		dbPassword: {
+			Description: "Password of the database user, required by the `postgres` driver",
+			Optional:    true,
+			Secret:      true,
+		},
 		dbSSLMode: {
+			Description:  "SSL mode of the database connection",
 			DefaultValue: "disable",
-			OneOf:        []string{"enable", "disable"},
+			OneOf:        []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full", sslModeEnable},
+		},
+		dbSSLRootCert: {
+			Description: "Path to the CA certificate verifying the database server, for the `verify-ca` and `verify-full` SSL modes",
+			Optional:    true,
+		},
+		dbSSLCert: {
+			Description: "Path to the client certificate presented to the database",
+			Optional:    true,
+		},
+		dbSSLKey: {
+			Description: "Path to the private key of the client certificate",
+			Optional:    true,
+		},
+		dbAppName: {
+			Description: "Name of the service reported to the database, e.g. in `pg_stat_activity`",
+			Optional:    true,
+		},
+		dbSearchPath: {
+			Description: "Comma-separated schemas searched for unqualified names",
+			Optional:    true,
+		},
+		dbStmtTimeout: {
+			Description: "Maximum duration of a statement before the database aborts it",
+			IsDuration:  true,
+			Optional:    true,
+		},
+		dbConnectTimeout: {
+			Description:  "Total time given to connect to the database at startup, including the retries",
+			DefaultValue: "1m",
+			IsDuration:   true,
+		},
+		dbConnectRetries: {
+			Description:  "Number of retries to connect to the database at startup, with an exponential backoff",
+			DefaultValue: "5",
+			IsInteger:    true,
+			Min:          "0",
+		},
+		dbSlowThreshold: {
+			Description:  "Duration above which a query is logged as slow, or `0s` to disable the slow query logs",
+			DefaultValue: gormzap.DefaultSlowThreshold.String(),
+			IsDuration:   true,
 		},
 		dbMaxOpenConn: {
+			Description:  "Maximum number of open connections to the database",
 			DefaultValue: "10",
 			IsInteger:    true,
 		},
 		dbMaxIdleConn: {
+			Description:  "Maximum number of idle connections to the database",
 			DefaultValue: "10",
 			IsInteger:    true,
 		},
 		dbMaxConnLifeTime: {
+			Description:  "Maximum amount of time a connection to the database may be reused",
 			DefaultValue: "1h",
+			IsDuration:   true,
+		},
+		dbReplicaHosts: {
+			Description: "Comma-separated `host` or `host:port` of the read replicas of the database",
+			IsList:      true,
+			Optional:    true,
+		},
+		dbAutoMigrate: {
+			Description:  "Whether the migrations are applied when the database is created",
+			DefaultValue: "true",
+			IsBoolean:    true,
 		},
 	}
-)
+}
+
+// DBOption configures a database created by NewDB.
+type DBOption func(o *dbOptions)
+
+// dbOptions contains the options of a database.
+type dbOptions struct {
+	envPrefix      string
+	migrations     fs.FS
+	migrationsDir  string
+	skipMigrations bool
+	logger         *zap.Logger
+	sources        []env.Source
+}
+
+// WithEnvPrefix reads the variables of the database with the given prefix, e.g. `ORDERS` reads
+// `ORDERS_DB_HOST` instead of `DB_HOST`.
+func WithEnvPrefix(prefix string) DBOption {
+	return func(o *dbOptions) {
+		if prefix != "" && !strings.HasSuffix(prefix, "_") {
+			prefix += "_"
+		}
+		o.envPrefix = prefix
+	}
+}
 
 // NewDB creates a new *gorm.DB the configuration of which is through environment variables.
-func NewDB(cfg *gorm.Config) (*gorm.DB, error) {
-	var db *gorm.DB
+//
+// When `DB_REPLICA_HOSTS` is set, queries are routed through the GORM resolver, i.e. reads go to the
+// replicas while writes and transactions go to the primary.
+func NewDB(cfg *gorm.Config, opts ...DBOption) (*gorm.DB, error) {
+	db, _, err := openDB(cfg, opts...)
+	return db, err
+}
+
+// WithMigrations reads the migrations from the given directory of the file system, e.g. an embed.FS.
+// By default, the migrations are read from `db/migrations` in the working directory.
+func WithMigrations(fsys fs.FS, dir string) DBOption {
+	return func(o *dbOptions) {
+		o.migrations = fsys
+		o.migrationsDir = dir
+	}
+}
+
+// WithoutMigrations disables applying the migrations when the database is created.
+func WithoutMigrations() DBOption {
+	return func(o *dbOptions) {
+		o.skipMigrations = true
+	}
+}
+
+// WithLogger reports the migrations applied to the database and the missing dotenv files to the given logger.
+// Defaults to the logger of echozap.
+func WithLogger(logger *zap.Logger) DBOption {
+	return func(o *dbOptions) {
+		o.logger = logger
+	}
+}
+
+// WithSources reads the variables of the database from the given sources, in order of precedence, e.g. the
+// EnvOpts.Sources of the service. By default, they are read from the environment of the process,
+// complemented by the dotenv files in the working directory.
+func WithSources(sources ...env.Source) DBOption {
+	return func(o *dbOptions) {
+		o.sources = sources
+	}
+}
 
-	// options are not used here
-	err := dbEnvs.SetEnv()
+// openDB creates a new *gorm.DB and returns the connections of its primary and replicas, by name.
+func openDB(cfg *gorm.Config, opts ...DBOption) (*gorm.DB, map[string]*sql.DB, error) {
+	dbConf, o, err := newDBConfig(opts...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	lifetime, err := time.ParseDuration(dbEnvs[dbMaxConnLifeTime].Value)
+	db, conns, err := dbConf.setup(cfg)
 	if err != nil {
-		lifetime = time.Hour
+		return nil, nil, err
 	}
 
-	dbConf := &dbConfig{
-		Hostname:        dbEnvs[dbHostname].Value,
-		Port:            dbEnvs[dbPort].IntValue,
-		Name:            dbEnvs[dbName].Value,
-		Username:        dbEnvs[dbUsername].Value,
-		Password:        dbEnvs[dbPassword].Value,
-		SSLMode:         dbEnvs[dbSSLMode].Value,
-		TimeZone:        time.UTC,
-		MaxIdleConn:     dbEnvs[dbMaxIdleConn].IntValue,
-		MaxOpenedConn:   dbEnvs[dbMaxOpenConn].IntValue,
-		ConnMaxLifetime: lifetime,
+	if !o.skipMigrations {
+		err = migrateDB(conns[dbPrimary], dbConf.driver(), o.migrations, o.migrationsDir, o.logger)
+		if err != nil {
+			closeConns(conns)
+			return nil, nil, err
+		}
 	}
 
-	db, err = dbConf.setup(cfg)
-	if err != nil {
-		return nil, err
+	return db, conns, nil
+}
+
+// closeConns closes the connections of a database which failed to be set up.
+func closeConns(conns map[string]*sql.DB) {
+	for _, conn := range conns {
+		_ = conn.Close()
 	}
+}
 
-	sqlDb, err := db.DB()
+// newDBOptions resolves the options of a database.
+func newDBOptions(opts ...DBOption) (*dbOptions, error) {
+	o := &dbOptions{
+		migrations:    os.DirFS("."),
+		migrationsDir: defaultMigrationsDir,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.logger == nil {
+		logger, err := echozap.New()
+		if err != nil {
+			return nil, err
+		}
+		o.logger = logger
+	}
+
+	return o, nil
+}
+
+// newDBConfig resolves the options of a database and reads its configuration from the environment variables.
+func newDBConfig(opts ...DBOption) (*dbConfig, *dbOptions, error) {
+	o, err := newDBOptions(opts...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	err = migrateDB(sqlDb)
+	dbEnvs := newDBEnvs().Prefixed(o.envPrefix)
+	// the environment type of the service, which isn't prefixed, selects the level of the query logs
+	dbEnvs[envType] = &env.Var{Optional: true}
+	if len(o.sources) > 0 {
+		err = dbEnvs.SetEnvFrom(o.sources...)
+	} else {
+		err = dbEnvs.SetEnvFromFiles(env.DotEnvFiles{}, o.logger)
+	}
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return db, nil
+	dbEnv := func(name string) *env.Var {
+		return dbEnvs[o.envPrefix+name]
+	}
+
+	if !dbEnv(dbAutoMigrate).BooleanValue {
+		o.skipMigrations = true
+	}
+
+	driver := dbEnv(dbDriverName).Value
+	if _, ok := lookupDriver(driver); !ok {
+		return nil, nil, fmt.Errorf("driver `%s` of variable `%s` is not registered, import its package, "+
+			"e.g. `github.com/ingka-group/fastecho/sqlite`", driver, o.envPrefix+dbDriverName)
+	}
+	if driver == DriverPostgres {
+		for _, name := range []string{dbUsername, dbPassword} {
+			if stringutils.IsEmpty(dbEnv(name).Value) {
+				return nil, nil, fmt.Errorf("variable `%s` is required by the `%s` driver", o.envPrefix+name, driver)
+			}
+		}
+	}
+
+	dbConf := &dbConfig{
+		Driver:          driver,
+		Hostname:        dbEnv(dbHostname).Value,
+		Port:            dbEnv(dbPort).IntValue,
+		Name:            dbEnv(dbName).Value,
+		Username:        dbEnv(dbUsername).Value,
+		Password:        dbEnv(dbPassword).Value,
+		SSLMode:         dbEnv(dbSSLMode).Value,
+		SSLRootCert:     dbEnv(dbSSLRootCert).Value,
+		SSLCert:         dbEnv(dbSSLCert).Value,
+		SSLKey:          dbEnv(dbSSLKey).Value,
+		AppName:         dbEnv(dbAppName).Value,
+		SearchPath:      dbEnv(dbSearchPath).Value,
+		StmtTimeout:     dbEnv(dbStmtTimeout).DurationValue,
+		ConnectTimeout:  dbEnv(dbConnectTimeout).DurationValue,
+		ConnectRetries:  dbEnv(dbConnectRetries).IntValue,
+		TimeZone:        time.UTC,
+		MaxIdleConn:     dbEnv(dbMaxIdleConn).IntValue,
+		MaxOpenedConn:   dbEnv(dbMaxOpenConn).IntValue,
+		ConnMaxLifetime: dbEnv(dbMaxConnLifeTime).DurationValue,
+		Replicas:        dbEnv(dbReplicaHosts).ListValue,
+		Alias:           strings.ToLower(strings.TrimSuffix(o.envPrefix, "_")),
+		SlowThreshold:   dbEnv(dbSlowThreshold).DurationValue,
+		LogLevel:        gormzap.LevelFor(dbEnvs[envType].Value),
+		Logger:          o.logger,
+	}
+
+	if driver == DriverSQLite {
+		if len(dbConf.Replicas) > 0 {
+			return nil, nil, fmt.Errorf("variable `%s` isn't supported by the `%s` driver", o.envPrefix+dbReplicaHosts, driver)
+		}
+
+		// every connection to an in-memory database opens a new one, so the pool keeps a single connection
+		if dbConf.Name == sqliteMemory {
+			dbConf.MaxOpenedConn = 1
+			dbConf.MaxIdleConn = 1
+			dbConf.ConnMaxLifetime = 0
+		}
+	}
+
+	return dbConf, o, nil
 }
 
 // dbConfig contains the database configuration.
 type dbConfig struct {
+	Driver          string
 	Hostname        string
 	Port            int
 	Name            string
 	Username        string
 	Password        string
 	SSLMode         string
+	SSLRootCert     string
+	SSLCert         string
+	SSLKey          string
+	AppName         string
+	SearchPath      string
+	StmtTimeout     time.Duration
+	ConnectTimeout  time.Duration
+	ConnectRetries  int
 	TimeZone        *time.Location
 	MaxIdleConn     int
 	MaxOpenedConn   int
 	ConnMaxLifetime time.Duration
+	Replicas        []string // `host` or `host:port` of the read replicas
+	Alias           string   // names the database in its metrics and spans
+	SlowThreshold   time.Duration
+	LogLevel        logger.LogLevel // level of the query logs
+	Logger          *zap.Logger
 }
 
-// setup creates a new database based on the configuration given.
-func (c *dbConfig) setup(cfg *gorm.Config) (*gorm.DB, error) {
-	dsn, err := c.buildDSN()
+// dbPrimary is the name of the connection to the primary database.
+const dbPrimary = "primary"
+
+// setup creates a new database based on the configuration given, and returns the connections of its
+// primary and replicas, by name.
+func (c *dbConfig) setup(cfg *gorm.Config) (*gorm.DB, map[string]*sql.DB, error) {
+	// the given config is left untouched, as it may be shared by several databases
+	if cfg == nil {
+		cfg = &gorm.Config{}
+	} else {
+		copied := *cfg
+		copied.Plugins = maps.Clone(cfg.Plugins)
+		cfg = &copied
+	}
+
+	// the queries are logged along with the other logs of the service, unless a logger is given
+	if cfg.Logger == nil {
+		cfg.Logger = gormzap.New(c.Logger,
+			gormzap.WithLogLevel(c.LogLevel),
+			gormzap.WithSlowThreshold(c.SlowThreshold),
+		)
+	}
+
+	db, sqlDb, err := c.open(cfg)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	if cfg == nil {
-		cfg = &gorm.Config{
-			Logger: logger.Default.LogMode(logger.Info),
+	conns := map[string]*sql.DB{dbPrimary: sqlDb}
+	replicaConns := make(map[string]*sql.DB, len(c.Replicas))
+	replicas := make([]gorm.Dialector, 0, len(c.Replicas))
+	for i, addr := range c.Replicas {
+		replicaConf, err := c.replica(addr)
+		if err != nil {
+			closeConns(conns)
+			return nil, nil, err
+		}
+
+		_, replicaDb, err := replicaConf.open(&gorm.Config{Logger: cfg.Logger})
+		if err != nil {
+			closeConns(conns)
+			return nil, nil, err
+		}
+
+		name := fmt.Sprintf("replica-%d", i+1)
+		conns[name] = replicaDb
+		replicaConns[name] = replicaDb
+		replicas = append(replicas, c.driver().dialector(replicaDb))
+	}
+
+	if len(replicas) > 0 {
+		err = db.Use(dbresolver.Register(dbresolver.Config{
+			Replicas: replicas,
+			Policy:   dbresolver.RandomPolicy{},
+		}))
+		if err != nil {
+			closeConns(conns)
+			return nil, nil, err
 		}
 	}
 
-	db, err := gorm.Open(
-		postgres.Open(dsn),
-		cfg,
-	)
+	// trace the queries and export their metrics, which are collected by the registry of the servers
+	err = db.Use(gormotel.New(
+		gormotel.WithDBName(c.Alias),
+		gormotel.WithReplicas(replicaConns),
+	))
 	if err != nil {
-		return nil, err
+		closeConns(conns)
+		return nil, nil, err
 	}
 
-	sqlDb, err := db.DB()
+	return db, conns, nil
+}
+
+// open opens a connection to the database and configures its pool.
+func (c *dbConfig) open(cfg *gorm.Config) (*gorm.DB, *sql.DB, error) {
+	sqlDb, err := c.connect()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	db, err := gorm.Open(c.driver().dialector(sqlDb), cfg)
+	if err != nil {
+		_ = sqlDb.Close()
+		return nil, nil, err
 	}
 
 	sqlDb.SetMaxIdleConns(c.MaxIdleConn)
 	sqlDb.SetMaxOpenConns(c.MaxOpenedConn)
 	sqlDb.SetConnMaxLifetime(c.ConnMaxLifetime)
 
-	return db, nil
+	return db, sqlDb, nil
+}
+
+// connect connects to the database. Failed attempts are retried with an exponential backoff until either
+// ConnectRetries or ConnectTimeout is exhausted, e.g. while a sidecar proxy to the database is starting.
+func (c *dbConfig) connect() (*sql.DB, error) {
+	dsn, err := c.buildDSN()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	if c.ConnectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.ConnectTimeout)
+		defer cancel()
+	}
+
+	logger := c.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	backoff := connectBackoff
+	var waited time.Duration // the backoff before the attempt
+	for attempt := 1; ; attempt++ {
+		logger.Info("Connecting to the database",
+			zap.String("host", c.Hostname),
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", waited),
+		)
+
+		sqlDb, err := sql.Open(c.driver().sqlDriver(), dsn)
+		if err != nil {
+			return nil, err
+		}
+
+		err = sqlDb.PingContext(ctx)
+		if err == nil {
+			logger.Info("Connected to the database", zap.String("host", c.Hostname), zap.Int("attempt", attempt))
+			return sqlDb, nil
+		}
+		_ = sqlDb.Close()
+
+		if attempt > c.ConnectRetries {
+			return nil, fmt.Errorf("failed to connect to the database after %d attempts: %w", attempt, err)
+		}
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("failed to connect to the database within %s: %w", c.ConnectTimeout, err)
+		}
+
+		logger.Warn("Failed to connect to the database, retrying",
+			zap.String("host", c.Hostname),
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to connect to the database within %s: %w", c.ConnectTimeout, err)
+		case <-time.After(backoff):
+		}
+
+		waited = backoff
+		backoff = min(2*backoff, maxConnectBackoff)
+	}
+}
+
+// replica returns the configuration of a read replica given its `host` or `host:port`. Other settings are
+// shared with the primary.
+func (c *dbConfig) replica(addr string) (*dbConfig, error) {
+	replica := *c
+	replica.Replicas = nil
+	replica.Hostname = addr
+
+	if host, port, err := net.SplitHostPort(addr); err == nil {
+		replica.Hostname = host
+		replica.Port, err = strconv.Atoi(port)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port of replica `%s`: %w", addr, err)
+		}
+	}
+
+	return &replica, nil
+}
+
+// driver returns the driver of the database, which defaults to Postgres.
+func (c *dbConfig) driver() dbDriver {
+	if driver, ok := lookupDriver(c.Driver); ok {
+		return driver
+	}
+
+	return postgresDriver{}
+}
+
+// String describes the database configuration with the password redacted, so that it can be logged safely.
+func (c *dbConfig) String() string {
+	return c.driver().dsn(c, true)
 }
 
 // BuildDSN builds the Data Source Name (DSN) which represents the database connection string.
 func (c *dbConfig) buildDSN() (string, error) {
-	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%v TimeZone=%s",
-		c.Hostname,
-		c.Username,
-		c.Password,
-		c.Name,
-		c.Port,
-		c.SSLMode,
-		c.TimeZone), nil
-}
-
-// migrateDB migrates the database to the latest version using goose.
-func migrateDB(db *sql.DB) error {
-	provider, err := goose.NewProvider(
-		goose.DialectPostgres,
-		db,
-		os.DirFS("db/migrations"),
-	)
+	return c.driver().dsn(c, false), nil
+}
+
+// postgresDSN builds the DSN of a Postgres database as a URL, so that the values are escaped, e.g. passwords
+// containing spaces or quotes.
+func (c *dbConfig) postgresDSN() *url.URL {
+	params := url.Values{}
+	params.Set("sslmode", c.SSLMode)
+	if c.SSLMode == sslModeEnable {
+		params.Set("sslmode", "require")
+	}
+
+	optional := map[string]string{
+		"sslrootcert":      c.SSLRootCert,
+		"sslcert":          c.SSLCert,
+		"sslkey":           c.SSLKey,
+		"application_name": c.AppName,
+		"search_path":      c.SearchPath,
+	}
+	for key, value := range optional {
+		if !stringutils.IsEmpty(value) {
+			params.Set(key, value)
+		}
+	}
 
+	if c.StmtTimeout > 0 {
+		params.Set("statement_timeout", strconv.FormatInt(c.StmtTimeout.Milliseconds(), 10))
+	}
+
+	if c.TimeZone != nil {
+		params.Set("TimeZone", c.TimeZone.String())
+	}
+
+	// a Unix socket directory can't be the host of the URL, so it's given as a parameter instead
+	host := net.JoinHostPort(c.Hostname, strconv.Itoa(c.Port))
+	if strings.HasPrefix(c.Hostname, "/") {
+		host = ""
+		params.Set("host", c.Hostname)
+		params.Set("port", strconv.Itoa(c.Port))
+	}
+
+	return &url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.Username, c.Password),
+		Host:     host,
+		Path:     "/" + c.Name,
+		RawQuery: params.Encode(),
+	}
+}
+
+// migrateDB migrates the database to the latest version using goose, with the migrations in the given
+// directory of the file system. With Postgres, the migrations are applied under an advisory lock, so that
+// replicas of the service starting at the same time don't race.
+func migrateDB(db *sql.DB, driver dbDriver, fsys fs.FS, dir string, logger *zap.Logger) error {
+	provider, err := newMigrationProvider(db, driver, fsys, dir)
 	if err != nil {
 		if errors.Is(err, goose.ErrNoMigrations) {
 			return nil
@@ -198,12 +668,43 @@ func migrateDB(db *sql.DB) error {
 		return err
 	}
 
-	for i := range results {
-		if results[i].Error != nil {
-			return results[i].Error
+	return logMigrationResults(logger, results...)
+}
+
+// newMigrationProvider creates the goose provider of the migrations in the given directory of the file system,
+// in the dialect of the driver, which locks the database while migrating it if needed.
+func newMigrationProvider(db *sql.DB, driver dbDriver, fsys fs.FS, dir string) (*goose.Provider, error) {
+	migrations, err := fs.Sub(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	locker, err := driver.sessionLocker()
+	if err != nil {
+		return nil, err
+	}
+
+	var options []goose.ProviderOption
+	if locker != nil {
+		options = append(options, goose.WithSessionLocker(locker))
+	}
+
+	return goose.NewProvider(driver.migrationDialect(), db, migrations, options...)
+}
+
+// logMigrationResults reports the applied migrations, and returns the error of the first one that failed.
+func logMigrationResults(logger *zap.Logger, results ...*goose.MigrationResult) error {
+	for _, result := range results {
+		if result.Error != nil {
+			return result.Error
 		}
 
-		log.Println("[", i+1, "] Migration applied:", results[i].Source.Path)
+		logger.Info("Migration applied",
+			zap.Int64("version", result.Source.Version),
+			zap.String("path", result.Source.Path),
+			zap.String("direction", result.Direction),
+			zap.Duration("duration", result.Duration),
+		)
 	}
 
 	return nil
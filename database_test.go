@@ -0,0 +1,395 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fastecho
+
+import (
+	"net"
+	"strconv"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"gorm.io/gorm"
+
+	"github.com/ingka-group/fastecho/env"
+	"github.com/ingka-group/fastecho/gormotel"
+	_ "github.com/ingka-group/fastecho/sqlite"
+)
+
+func TestWithEnvPrefix(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		expect string
+	}{
+		{name: "ok: no prefix", prefix: "", expect: ""},
+		{name: "ok: prefix", prefix: "ORDERS", expect: "ORDERS_"},
+		{name: "ok: prefix with separator", prefix: "ORDERS_", expect: "ORDERS_"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &dbOptions{}
+			WithEnvPrefix(tt.prefix)(o)
+			assert.Equal(t, tt.expect, o.envPrefix)
+		})
+	}
+}
+
+func TestNewDB_sqlite(t *testing.T) {
+	t.Setenv(dbDriverName, DriverSQLite)
+	t.Setenv(dbName, sqliteMemory)
+
+	migrations := fstest.MapFS{
+		"migrations/00001_create_orders.sql": {Data: []byte(`-- +goose Up
+CREATE TABLE orders (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+
+-- +goose Down
+DROP TABLE orders;
+`)},
+	}
+
+	db, err := NewDB(nil, WithMigrations(migrations, "migrations"), WithLogger(zap.NewNop()))
+	require.NoError(t, err)
+
+	type order struct {
+		ID   int
+		Name string
+	}
+
+	require.NoError(t, db.Create(&order{Name: "chair"}).Error)
+
+	var orders []order
+	require.NoError(t, db.Find(&orders).Error)
+	assert.Equal(t, []order{{ID: 1, Name: "chair"}}, orders)
+}
+
+func TestNewDB_logger(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv(dbDriverName, DriverSQLite)
+	t.Setenv(dbName, sqliteMemory)
+
+	core, logs := observer.New(zap.InfoLevel)
+	_, err := NewDB(nil, WithoutMigrations(), WithLogger(zap.New(core)))
+	require.NoError(t, err)
+
+	// the missing dotenv files are reported to the logger of the database, not the global one
+	assert.Equal(t, 1, logs.FilterMessageSnippet("Configuration files don't exist").Len())
+}
+
+func TestNewDB_config(t *testing.T) {
+	t.Setenv(dbDriverName, DriverSQLite)
+	t.Setenv(dbName, sqliteMemory)
+
+	// the config given is shared by the databases, and left untouched
+	cfg := &gorm.Config{}
+	for range 2 {
+		db, err := NewDB(cfg, WithoutMigrations(), WithLogger(zap.NewNop()))
+		require.NoError(t, err)
+		assert.Contains(t, db.Config.Plugins, gormotel.PluginName)
+	}
+
+	assert.Nil(t, cfg.Logger)
+	assert.Empty(t, cfg.Plugins)
+}
+
+func TestNewDB_withSources(t *testing.T) {
+	// the environment of the process is not read when the sources are given
+	t.Setenv("ORDERS_"+dbDriverName, DriverPostgres)
+
+	sources := env.Vars("config", map[string]string{
+		"ORDERS_" + dbDriverName: DriverSQLite,
+		"ORDERS_" + dbName:       sqliteMemory,
+	})
+
+	db, err := NewDB(nil, WithEnvPrefix("ORDERS"), WithSources(sources), WithoutMigrations(), WithLogger(zap.NewNop()))
+	require.NoError(t, err)
+	assert.Equal(t, DriverSQLite, db.Dialector.Name())
+}
+
+func TestNewDB_logLevel(t *testing.T) {
+	// the environment of the process is not read when the sources are given
+	t.Setenv(envType, devEnv)
+
+	tests := []struct {
+		name       string
+		envType    string
+		expectLogs int
+	}{
+		{name: "ok: statements are logged in dev", envType: devEnv, expectLogs: 1},
+		{name: "ok: statements are not logged in prod", envType: prodEnv, expectLogs: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sources := env.Vars("config", map[string]string{
+				envType:      tt.envType,
+				dbDriverName: DriverSQLite,
+				dbName:       sqliteMemory,
+			})
+
+			core, logs := observer.New(zap.DebugLevel)
+			db, err := NewDB(nil, WithSources(sources), WithoutMigrations(), WithLogger(zap.New(core)))
+			require.NoError(t, err)
+
+			require.NoError(t, db.Exec("SELECT 1").Error)
+			assert.Equal(t, tt.expectLogs, logs.FilterMessage("Query").Len())
+		})
+	}
+}
+
+func TestNewDB_unregisteredDriver(t *testing.T) {
+	t.Setenv(dbDriverName, "mysql")
+	t.Setenv(dbName, "test")
+
+	_, err := NewDB(nil, WithLogger(zap.NewNop()))
+	assert.ErrorContains(t, err, "driver `mysql` of variable `DB_DRIVER` is not registered")
+}
+
+func TestNewDB_postgresRequiresCredentials(t *testing.T) {
+	t.Setenv(dbDriverName, DriverPostgres)
+	t.Setenv(dbName, "orders")
+	t.Setenv(dbUsername, "")
+	t.Setenv(dbPassword, "")
+
+	_, err := NewDB(nil, WithLogger(zap.NewNop()))
+	assert.ErrorContains(t, err, "variable `DB_READ_WRITE_USER` is required by the `postgres` driver")
+}
+
+func TestDBConfig_replica(t *testing.T) {
+	primary := &dbConfig{
+		Hostname: "primary",
+		Port:     5432,
+		Name:     "orders",
+		Replicas: []string{"replica-a", "replica-b:5433"},
+	}
+
+	tests := []struct {
+		name       string
+		addr       string
+		expectHost string
+		expectPort int
+		expectErr  bool
+	}{
+		{name: "ok: host", addr: "replica-a", expectHost: "replica-a", expectPort: 5432},
+		{name: "ok: host and port", addr: "replica-b:5433", expectHost: "replica-b", expectPort: 5433},
+		{name: "error: invalid port", addr: "replica-c:abc", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replica, err := primary.replica(tt.addr)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectHost, replica.Hostname)
+			assert.Equal(t, tt.expectPort, replica.Port)
+			assert.Equal(t, "orders", replica.Name)
+			assert.Empty(t, replica.Replicas)
+		})
+	}
+}
+
+func TestDBConfig_buildDSN(t *testing.T) {
+	tests := []struct {
+		name     string
+		hostname string
+		password string
+		sslMode  string
+	}{
+		{
+			name:     "ok: TCP host",
+			hostname: "db.example.com",
+			password: "secret",
+			sslMode:  "verify-full",
+		},
+		{
+			name:     "ok: Unix socket directory",
+			hostname: "/var/run/postgresql",
+			password: "secret",
+			sslMode:  "disable",
+		},
+		{
+			name:     "ok: password with special characters",
+			hostname: "db.example.com",
+			password: `p@ss word'"/?%25%`,
+			sslMode:  "verify-full",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &dbConfig{
+				Hostname:    tt.hostname,
+				Port:        5433,
+				Name:        "orders",
+				Username:    "service",
+				Password:    tt.password,
+				SSLMode:     tt.sslMode,
+				AppName:     "orders api",
+				SearchPath:  "orders,public",
+				StmtTimeout: 30 * time.Second,
+				TimeZone:    time.UTC,
+			}
+
+			dsn, err := cfg.buildDSN()
+			require.NoError(t, err)
+
+			parsed, err := pgconn.ParseConfig(dsn)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.hostname, parsed.Host)
+			assert.Equal(t, uint16(5433), parsed.Port)
+			assert.Equal(t, "orders", parsed.Database)
+			assert.Equal(t, "service", parsed.User)
+			assert.Equal(t, tt.password, parsed.Password)
+			assert.Equal(t, "orders api", parsed.RuntimeParams["application_name"])
+			assert.Equal(t, "orders,public", parsed.RuntimeParams["search_path"])
+			assert.Equal(t, "30000", parsed.RuntimeParams["statement_timeout"])
+			assert.Equal(t, "UTC", parsed.RuntimeParams["TimeZone"])
+			assert.Equal(t, tt.sslMode != "disable", parsed.TLSConfig != nil)
+
+			assert.NotContains(t, cfg.String(), tt.password)
+			assert.Contains(t, cfg.String(), "service:xxxxx@")
+		})
+	}
+}
+
+func TestDBConfig_buildDSN_sslModeEnable(t *testing.T) {
+	cfg := &dbConfig{Hostname: "localhost", Port: 5432, Name: "orders", SSLMode: sslModeEnable}
+
+	dsn, err := cfg.buildDSN()
+	require.NoError(t, err)
+	assert.Contains(t, dsn, "sslmode=require")
+}
+
+func TestDatabases_NewDB(t *testing.T) {
+	// Nothing listens on the port, so connecting to `orders` lasts until the timeout
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().(*net.TCPAddr)
+	require.NoError(t, ln.Close())
+
+	t.Setenv("ORDERS_"+dbDriverName, DriverPostgres)
+	t.Setenv("ORDERS_"+dbHostname, "127.0.0.1")
+	t.Setenv("ORDERS_"+dbPort, strconv.Itoa(addr.Port))
+	t.Setenv("ORDERS_"+dbName, "orders")
+	t.Setenv("ORDERS_"+dbUsername, "service")
+	t.Setenv("ORDERS_"+dbPassword, "secret")
+	t.Setenv("ORDERS_"+dbConnectTimeout, "500ms")
+	t.Setenv("STOCK_"+dbDriverName, DriverSQLite)
+	t.Setenv("STOCK_"+dbName, sqliteMemory)
+
+	dbs := NewDatabases()
+	core, logs := observer.New(zap.InfoLevel)
+
+	connectErr := make(chan error, 1)
+	go func() {
+		_, err := dbs.NewDB("orders", nil, WithoutMigrations(), WithLogger(zap.New(core)))
+		connectErr <- err
+	}()
+	require.Eventually(t, func() bool {
+		return logs.FilterMessage("Connecting to the database").Len() > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the other databases are available while `orders` is connecting
+	stock, err := dbs.NewDB("stock", nil, WithoutMigrations(), WithLogger(zap.NewNop()))
+	require.NoError(t, err)
+	assert.Same(t, stock, dbs.Get("stock"))
+	assert.Equal(t, []*gorm.DB{stock}, dbs.all())
+
+	_, err = dbs.NewDB("orders", nil, WithoutMigrations(), WithLogger(zap.NewNop()))
+	assert.ErrorContains(t, err, "database `orders` is already registered")
+	assert.Nil(t, dbs.Get("orders"))
+
+	// the name is released once connecting failed
+	require.Error(t, <-connectErr)
+	dbs.mu.RLock()
+	assert.NotContains(t, dbs.dbs, "orders")
+	dbs.mu.RUnlock()
+}
+
+func TestNewDB_connect(t *testing.T) {
+	// Nothing listens on the port, so every attempt fails
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().(*net.TCPAddr)
+	require.NoError(t, ln.Close())
+
+	tests := []struct {
+		name           string
+		retries        string
+		timeout        string
+		expectAttempts int
+		expectRetries  int
+		expectErr      string
+	}{
+		{
+			name:           "error: retries exhausted",
+			retries:        "2",
+			timeout:        "1m",
+			expectAttempts: 3,
+			expectRetries:  2,
+			expectErr:      "after 3 attempts",
+		},
+		{
+			name:           "error: timeout exhausted",
+			retries:        "100",
+			timeout:        "100ms",
+			expectAttempts: 1, // the timeout expires during the backoff
+			expectRetries:  1,
+			expectErr:      "within 100ms",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(dbDriverName, DriverPostgres)
+			t.Setenv(dbHostname, "127.0.0.1")
+			t.Setenv(dbPort, strconv.Itoa(addr.Port))
+			t.Setenv(dbName, "orders")
+			t.Setenv(dbUsername, "service")
+			t.Setenv(dbPassword, "secret")
+			t.Setenv(dbConnectRetries, tt.retries)
+			t.Setenv(dbConnectTimeout, tt.timeout)
+
+			core, logs := observer.New(zap.InfoLevel)
+
+			_, err := NewDB(nil, WithoutMigrations(), WithLogger(zap.New(core)))
+			assert.ErrorContains(t, err, tt.expectErr)
+
+			// every attempt is logged with its number and the backoff before it
+			attempts := logs.FilterMessage("Connecting to the database").AllUntimed()
+			require.Len(t, attempts, tt.expectAttempts)
+			backoff := time.Duration(0)
+			for i, attempt := range attempts {
+				assert.Equal(t, int64(i+1), attempt.ContextMap()["attempt"])
+				assert.Equal(t, backoff, attempt.ContextMap()["backoff"])
+				backoff = max(connectBackoff, 2*backoff)
+			}
+
+			retries := logs.FilterMessage("Failed to connect to the database, retrying").Len()
+			assert.Equal(t, tt.expectRetries, retries)
+		})
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fastecho
+
+import (
+	"database/sql"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+
+	"github.com/ingka-group/fastecho/health"
+)
+
+// Databases is a registry of the named databases of a service.
+type Databases struct {
+	mu    sync.RWMutex
+	dbs   map[string]*gorm.DB           // nil while the database is connecting
+	conns map[string]map[string]*sql.DB // the connections of the primary and the replicas of each database
+}
+
+// NewDatabases creates an empty registry of databases.
+func NewDatabases() *Databases {
+	return &Databases{
+		dbs:   make(map[string]*gorm.DB),
+		conns: make(map[string]map[string]*sql.DB),
+	}
+}
+
+// NewDB creates a new *gorm.DB like NewDB and registers it under the given name. The variables of the
+// database are prefixed with the upper-cased name, e.g. `orders` reads `ORDERS_DB_HOST`. An empty name
+// reads the `DB_*` variables.
+//
+// The name is reserved while connecting, which doesn't hold up the other databases of the registry.
+func (d *Databases) NewDB(name string, cfg *gorm.Config, opts ...DBOption) (*gorm.DB, error) {
+	err := d.reserve(name)
+	if err != nil {
+		return nil, err
+	}
+
+	opts = append([]DBOption{WithEnvPrefix(strings.ToUpper(name))}, opts...)
+	db, conns, err := openDB(cfg, opts...)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err != nil {
+		delete(d.dbs, name)
+		return nil, err
+	}
+
+	d.dbs[name] = db
+	d.conns[name] = conns
+
+	return db, nil
+}
+
+// reserve reserves the given name for a database which is connecting.
+func (d *Databases) reserve(name string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.dbs[name]; ok {
+		return fmt.Errorf("database `%s` is already registered", name)
+	}
+	d.dbs[name] = nil
+
+	return nil
+}
+
+// Get returns the database registered under the given name, or nil if there is none.
+func (d *Databases) Get(name string) *gorm.DB {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.dbs[name]
+}
+
+// all returns all the databases, or none if the registry is nil.
+func (d *Databases) all() []*gorm.DB {
+	if d == nil {
+		return nil
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	// the databases which are connecting are left out
+	return slices.DeleteFunc(slices.Collect(maps.Values(d.dbs)), func(db *gorm.DB) bool {
+		return db == nil
+	})
+}
+
+// pingers returns the connections of all the databases, by name, or none if the registry is nil.
+func (d *Databases) pingers() map[string]health.Pinger {
+	if d == nil {
+		return nil
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	pingers := make(map[string]health.Pinger)
+	for dbName, conns := range d.conns {
+		for connName, conn := range conns {
+			pingers[databaseConnName(dbName, connName)] = conn
+		}
+	}
+
+	return pingers
+}
+
+// databaseConnName names a connection of a database, e.g. `orders/replica-1`.
+func databaseConnName(dbName, connName string) string {
+	if dbName == "" {
+		dbName = "default"
+	}
+
+	return dbName + "/" + connName
+}
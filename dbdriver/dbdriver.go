@@ -0,0 +1,76 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dbdriver registers the drivers of the databases created by fastecho.NewDB besides Postgres, which are
+// selected by DB_DRIVER. Drivers are registered by importing their package, e.g. fastecho/sqlite, so that
+// services only link the drivers they use.
+package dbdriver
+
+import (
+	"database/sql"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/pressly/goose/v3"
+	"gorm.io/gorm"
+)
+
+// Driver opens a kind of database and migrates it.
+type Driver struct {
+	// SQLDriver is the name of the database/sql driver.
+	SQLDriver string
+	// DSN returns the connection string of the database with the given name, i.e. the value of DB_NAME.
+	DSN func(name string) string
+	// Dialector returns the GORM dialector over a connection.
+	Dialector func(conn *sql.DB) gorm.Dialector
+	// MigrationDialect is the goose dialect of the migrations, which are applied without a lock.
+	MigrationDialect goose.Dialect
+}
+
+var (
+	mu      sync.RWMutex
+	drivers = make(map[string]Driver)
+)
+
+// Register makes a driver available under the given name. It panics if a driver is registered twice under
+// the same name, like sql.Register.
+func Register(name string, driver Driver) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, ok := drivers[name]; ok {
+		panic(fmt.Sprintf("dbdriver: driver `%s` is already registered", name))
+	}
+
+	drivers[name] = driver
+}
+
+// Lookup returns the driver registered under the given name.
+func Lookup(name string) (Driver, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	driver, ok := drivers[name]
+	return driver, ok
+}
+
+// Names returns the names of the registered drivers, sorted.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return slices.Sorted(maps.Keys(drivers))
+}
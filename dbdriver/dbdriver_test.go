@@ -0,0 +1,37 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbdriver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegister(t *testing.T) {
+	Register("test", Driver{SQLDriver: "test-sql"})
+
+	driver, ok := Lookup("test")
+	assert.True(t, ok)
+	assert.Equal(t, "test-sql", driver.SQLDriver)
+	assert.Contains(t, Names(), "test")
+
+	_, ok = Lookup("unknown")
+	assert.False(t, ok)
+
+	assert.Panics(t, func() {
+		Register("test", Driver{})
+	})
+}
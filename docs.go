@@ -0,0 +1,84 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fastecho
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ingka-group/fastecho/env"
+	"github.com/ingka-group/fastecho/errs"
+)
+
+const (
+	// printEnvDocsFlag makes Run print the documentation of the environment variables instead of starting
+	// the server, e.g. `--print-env-docs` or `--print-env-docs=dotenv`.
+	printEnvDocsFlag = "--print-env-docs"
+
+	EnvDocsMarkdown   = "markdown"
+	EnvDocsDotEnv     = "dotenv"
+	EnvDocsJSONSchema = "jsonschema"
+)
+
+// envDocsFormat returns the documentation format requested by the command-line arguments, if any.
+// The format defaults to Markdown.
+func envDocsFormat(args []string) (string, bool) {
+	for _, arg := range args {
+		if arg == printEnvDocsFlag {
+			return EnvDocsMarkdown, true
+		}
+
+		if format, ok := strings.CutPrefix(arg, printEnvDocsFlag+"="); ok {
+			return format, true
+		}
+	}
+
+	return "", false
+}
+
+// PrintEnvDocs writes the documentation of all the environment variables declared by fastecho and the
+// given Config in the given format, i.e. EnvDocsMarkdown, EnvDocsDotEnv or EnvDocsJSONSchema.
+func PrintEnvDocs(w io.Writer, cfg *Config, format string) error {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+
+	extra, err := extraEnvMap(cfg)
+	if err != nil {
+		return err
+	}
+
+	sections := []env.Section{
+		{Title: "Server", Map: newEnvs()},
+		{Title: "TLS", Map: newTLSEnvs()},
+		{Title: "Database", Map: newDBEnvs()},
+	}
+	if len(extra) > 0 {
+		sections = append(sections, env.Section{Title: "Service", Map: extra})
+	}
+
+	switch format {
+	case EnvDocsMarkdown:
+		return env.WriteMarkdown(w, sections...)
+	case EnvDocsDotEnv:
+		return env.WriteDotEnvExample(w, sections...)
+	case EnvDocsJSONSchema:
+		return env.WriteJSONSchema(w, sections...)
+	default:
+		return errs.New(fmt.Sprintf("unknown env docs format `%s`, expected one of %v",
+			format, []string{EnvDocsMarkdown, EnvDocsDotEnv, EnvDocsJSONSchema}))
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fastecho
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/ingka-group/fastecho/dbdriver"
+)
+
+// Drivers of the databases, selected by DB_DRIVER.
+const (
+	DriverPostgres = "postgres"
+	// DriverSQLite is a pure-Go SQLite, e.g. for tests and local runs, which is registered by importing the
+	// fastecho/sqlite package. DB_NAME is the path of the database file, or `:memory:` for an in-memory database.
+	DriverSQLite = "sqlite"
+
+	// sqliteMemory is the name of an in-memory SQLite database.
+	sqliteMemory = ":memory:"
+)
+
+// dbDriver connects to a kind of database and migrates it.
+type dbDriver interface {
+	// sqlDriver returns the name of the database/sql driver.
+	sqlDriver() string
+	// dsn returns the connection string of the database and, with redacted, a description which can be logged.
+	dsn(c *dbConfig, redacted bool) string
+	// dialector returns the GORM dialector over a connection.
+	dialector(conn *sql.DB) gorm.Dialector
+	// migrationDialect returns the goose dialect of the migrations.
+	migrationDialect() goose.Dialect
+	// sessionLocker returns the lock taken while migrating, or nil if the database needs none.
+	sessionLocker() (lock.SessionLocker, error)
+}
+
+// lookupDriver returns the driver with the given name, i.e. Postgres or one registered with dbdriver.Register.
+func lookupDriver(name string) (dbDriver, bool) {
+	if name == DriverPostgres {
+		return postgresDriver{}, true
+	}
+
+	driver, ok := dbdriver.Lookup(name)
+	if !ok {
+		return nil, false
+	}
+
+	return registeredDriver{driver}, true
+}
+
+// postgresDriver connects to Postgres with pgx.
+type postgresDriver struct{}
+
+func (postgresDriver) sqlDriver() string {
+	return "pgx"
+}
+
+func (postgresDriver) dsn(c *dbConfig, redacted bool) string {
+	if redacted {
+		return c.postgresDSN().Redacted()
+	}
+
+	return c.postgresDSN().String()
+}
+
+func (postgresDriver) dialector(conn *sql.DB) gorm.Dialector {
+	return postgres.New(postgres.Config{Conn: conn})
+}
+
+func (postgresDriver) migrationDialect() goose.Dialect {
+	return goose.DialectPostgres
+}
+
+// sessionLocker returns a Postgres advisory lock, so that replicas of the service starting at the same time
+// don't race to migrate the database.
+func (postgresDriver) sessionLocker() (lock.SessionLocker, error) {
+	return lock.NewPostgresSessionLocker()
+}
+
+// registeredDriver adapts a driver registered with dbdriver.Register.
+type registeredDriver struct {
+	dbdriver.Driver
+}
+
+func (d registeredDriver) sqlDriver() string {
+	return d.SQLDriver
+}
+
+// dsn returns the connection string of the database. Registered drivers have no credentials, so the DSN is
+// never redacted.
+func (d registeredDriver) dsn(c *dbConfig, _ bool) string {
+	return d.DSN(c.Name)
+}
+
+func (d registeredDriver) dialector(conn *sql.DB) gorm.Dialector {
+	return d.Dialector(conn)
+}
+
+func (d registeredDriver) migrationDialect() goose.Dialect {
+	return d.MigrationDialect
+}
+
+// sessionLocker returns nil, as the registered drivers are meant for databases used by a single process.
+func (registeredDriver) sessionLocker() (lock.SessionLocker, error) {
+	return nil, nil
+}
@@ -0,0 +1,302 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package env
+
+import (
+	"math"
+	"net/url"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ingka-group/fastecho/stringutils"
+)
+
+// Struct tags used to declare environment variables on struct fields.
+const (
+	tagName     = "env"
+	tagDesc     = "desc"
+	tagDefault  = "default"
+	tagOneOf    = "oneof"
+	tagOptional = "optional"
+	tagSecret   = "secret"
+	tagMin      = "min"
+	tagMax      = "max"
+	tagPattern  = "pattern"
+	tagByteSize = "bytesize"
+)
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	urlType      = reflect.TypeOf(url.URL{})
+)
+
+// Bind populates the struct v points to from the environment, as SetEnv does for a Map.
+//
+// Each field is declared with struct tags, and its kind follows the type of the field:
+//
+//	type Config struct {
+//		Host    string        `env:"DB_HOST" default:"localhost" desc:"Hostname of the database"`
+//		Port    int           `env:"DB_PORT" default:"5432" min:"1" max:"65535"`
+//		SSLMode string        `env:"DB_SSL_MODE" default:"disable" oneof:"disable,require"`
+//		Timeout time.Duration `env:"DB_TIMEOUT" default:"5s"`
+//		Hosts   []string      `env:"DB_HOSTS" optional:"true"`
+//		Limit   int64         `env:"BODY_LIMIT" default:"1MB" bytesize:"true"`
+//		Token   string        `env:"API_TOKEN" secret:"true"`
+//	}
+//
+// Supported types are string, bool, integers, floats, time.Duration, []string and url.URL, or pointers to them.
+// Nested structs without an `env` tag are bound recursively.
+func Bind(v any) error {
+	return BindFrom(v)
+}
+
+// BindFrom populates the struct v points to from the given sources, as SetEnvFrom does for a Map.
+// Without sources, the variables are read as SetEnv does.
+func BindFrom(v any, sources ...Source) error {
+	m, err := MapOf(v)
+	if err != nil {
+		return err
+	}
+
+	if len(sources) > 0 {
+		err = m.SetEnvFrom(sources...)
+	} else {
+		err = m.SetEnv()
+	}
+	if err != nil {
+		return err
+	}
+
+	return m.Populate(v)
+}
+
+// MapOf builds a Map from the struct tags of the struct v points to.
+func MapOf(v any) (Map, error) {
+	rv, err := structValue(v)
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(Map)
+	err = walk(rv, func(name string, field reflect.StructField, _ reflect.Value) error {
+		variable, err := varOf(field)
+		if err != nil {
+			return errors.Wrapf(err, "field `%s`", field.Name)
+		}
+		m[name] = variable
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Populate sets the fields of the struct v points to from the values of the Map, once they are set.
+func (m Map) Populate(v any) error {
+	rv, err := structValue(v)
+	if err != nil {
+		return err
+	}
+
+	return walk(rv, func(name string, field reflect.StructField, fv reflect.Value) error {
+		variable, ok := m[name]
+		if !ok {
+			return errors.Errorf("variable `%s` of field `%s` is not declared", name, field.Name)
+		}
+
+		return errors.Wrapf(assign(fv, variable), "field `%s`", field.Name)
+	})
+}
+
+// structValue returns the struct v points to.
+func structValue(v any) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, errors.Errorf("a pointer to a struct is required, got %T", v)
+	}
+
+	return rv.Elem(), nil
+}
+
+// walk calls fn for each field of the struct that declares an environment variable.
+func walk(rv reflect.Value, fn func(name string, field reflect.StructField, fv reflect.Value) error) error {
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, ok := field.Tag.Lookup(tagName)
+		if !ok {
+			if field.Type.Kind() == reflect.Struct && field.Type != urlType {
+				if err := walk(rv.Field(i), fn); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if stringutils.IsEmpty(name) || name == "-" {
+			continue
+		}
+
+		if err := fn(name, field, rv.Field(i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// varOf creates a Var from the struct tags and the type of the field.
+func varOf(field reflect.StructField) (*Var, error) {
+	v := &Var{
+		Description:  field.Tag.Get(tagDesc),
+		DefaultValue: field.Tag.Get(tagDefault),
+		Min:          field.Tag.Get(tagMin),
+		Max:          field.Tag.Get(tagMax),
+		Pattern:      field.Tag.Get(tagPattern),
+	}
+
+	if oneOf := field.Tag.Get(tagOneOf); !stringutils.IsEmpty(oneOf) {
+		v.OneOf = splitList(oneOf)
+	}
+
+	var err error
+	if v.Optional, err = boolTag(field, tagOptional); err != nil {
+		return nil, err
+	}
+
+	if v.Secret, err = boolTag(field, tagSecret); err != nil {
+		return nil, err
+	}
+
+	byteSize, err := boolTag(field, tagByteSize)
+	if err != nil {
+		return nil, err
+	}
+
+	t := field.Type
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == durationType:
+		v.IsDuration = true
+	case t == urlType:
+		v.IsURL = true
+	case byteSize:
+		if t.Kind() != reflect.Int64 && t.Kind() != reflect.Int {
+			return nil, errors.Errorf("byte sizes require an int or int64 field, got %s", t)
+		}
+		v.IsByteSize = true
+	case t.Kind() == reflect.String:
+	case t.Kind() == reflect.Bool:
+		v.IsBoolean = true
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		// the values are parsed as an int, so the upper range of uint64 fields is not supported
+		if maxValue, err := strconv.ParseUint(v.Max, 10, 64); err == nil && maxValue > math.MaxInt {
+			return nil, errors.Errorf("max %s of %s exceeds the largest supported integer %d", v.Max, t, math.MaxInt)
+		}
+		v.IsInteger = true
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		v.IsFloat = true
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.String:
+		v.IsList = true
+	default:
+		return nil, errors.Errorf("unsupported type %s", field.Type)
+	}
+
+	return v, nil
+}
+
+// boolTag returns the boolean value of a struct tag, which is false when the tag is missing.
+func boolTag(field reflect.StructField, tag string) (bool, error) {
+	value, ok := field.Tag.Lookup(tag)
+	if !ok {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errors.Errorf("tag `%s` requires a boolean value", tag)
+	}
+
+	return b, nil
+}
+
+// assign sets the field to the typed value of the variable.
+func assign(fv reflect.Value, v *Var) error {
+	// Optional variables without a value leave the field untouched
+	if stringutils.IsEmpty(v.Value) {
+		return nil
+	}
+
+	if fv.Kind() == reflect.Pointer {
+		if fv.IsNil() {
+			fv.Set(reflect.New(fv.Type().Elem()))
+		}
+		fv = fv.Elem()
+	}
+
+	switch {
+	case v.IsDuration:
+		fv.SetInt(int64(v.DurationValue))
+	case v.IsURL:
+		fv.Set(reflect.ValueOf(*v.URLValue))
+	case v.IsByteSize:
+		fv.SetInt(v.ByteSizeValue)
+	case v.IsBoolean:
+		fv.SetBool(v.BooleanValue)
+	case v.IsInteger:
+		return setInteger(fv, v.IntValue)
+	case v.IsFloat:
+		if fv.OverflowFloat(v.FloatValue) {
+			return errors.Errorf("value %v overflows %s", v.FloatValue, fv.Type())
+		}
+		fv.SetFloat(v.FloatValue)
+	case v.IsList:
+		fv.Set(reflect.ValueOf(v.ListValue))
+	default:
+		fv.SetString(v.Value)
+	}
+
+	return nil
+}
+
+// setInteger sets a signed or unsigned integer field, checking for overflows.
+func setInteger(fv reflect.Value, n int) error {
+	if fv.Kind() >= reflect.Uint && fv.Kind() <= reflect.Uint64 {
+		if n < 0 || fv.OverflowUint(uint64(n)) {
+			return errors.Errorf("value %d overflows %s", n, fv.Type())
+		}
+		fv.SetUint(uint64(n))
+		return nil
+	}
+
+	if fv.OverflowInt(int64(n)) {
+		return errors.Errorf("value %d overflows %s", n, fv.Type())
+	}
+	fv.SetInt(int64(n))
+
+	return nil
+}
@@ -0,0 +1,137 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package env
+
+import (
+	"math"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testDBConfig struct {
+	Host    string        `env:"DB_HOST" default:"localhost"`
+	Port    int           `env:"DB_PORT" default:"5432" min:"1" max:"65535"`
+	SSLMode string        `env:"DB_SSL_MODE" default:"disable" oneof:"disable,require"`
+	Timeout time.Duration `env:"DB_TIMEOUT" default:"5s"`
+}
+
+type testConfig struct {
+	DB       testDBConfig
+	Debug    bool     `env:"DEBUG" default:"false"`
+	Ratio    float64  `env:"RATIO" default:"0.5"`
+	Hosts    []string `env:"HOSTS" optional:"true"`
+	Limit    int64    `env:"BODY_LIMIT" default:"1KiB" bytesize:"true"`
+	Endpoint *url.URL `env:"ENDPOINT" default:"https://example.com"`
+	Retries  *uint8   `env:"RETRIES" optional:"true"`
+	MaxSize  uint64   `env:"MAX_SIZE" optional:"true"`
+	ignored  string
+}
+
+func TestBindFrom(t *testing.T) {
+	tests := []struct {
+		name      string
+		dotEnv    string
+		expect    func(t *testing.T, cfg testConfig)
+		expectErr bool
+	}{
+		{
+			name:   "ok: defaults",
+			dotEnv: "",
+			expect: func(t *testing.T, cfg testConfig) {
+				assert.Equal(t, "localhost", cfg.DB.Host)
+				assert.Equal(t, 5432, cfg.DB.Port)
+				assert.Equal(t, 5*time.Second, cfg.DB.Timeout)
+				assert.Equal(t, 0.5, cfg.Ratio)
+				assert.Nil(t, cfg.Hosts)
+				assert.Equal(t, int64(1024), cfg.Limit)
+				assert.Equal(t, "example.com", cfg.Endpoint.Host)
+				assert.Nil(t, cfg.Retries)
+			},
+		},
+		{
+			name:   "ok: values",
+			dotEnv: "DB_PORT=6543\nDB_SSL_MODE=require\nDEBUG=true\nHOSTS=a,b\nRETRIES=3\nMAX_SIZE=9223372036854775807\n",
+			expect: func(t *testing.T, cfg testConfig) {
+				assert.Equal(t, 6543, cfg.DB.Port)
+				assert.Equal(t, "require", cfg.DB.SSLMode)
+				assert.True(t, cfg.Debug)
+				assert.Equal(t, []string{"a", "b"}, cfg.Hosts)
+				require.NotNil(t, cfg.Retries)
+				assert.Equal(t, uint8(3), *cfg.Retries)
+				assert.Equal(t, uint64(math.MaxInt64), cfg.MaxSize)
+			},
+		},
+		{
+			name:      "error: value not in oneof",
+			dotEnv:    "DB_SSL_MODE=verify-full",
+			expectErr: true,
+		},
+		{
+			name:      "error: value out of bounds",
+			dotEnv:    "DB_PORT=70000",
+			expectErr: true,
+		},
+		{
+			name:      "error: value overflows the field",
+			dotEnv:    "RETRIES=300",
+			expectErr: true,
+		},
+		{
+			name:      "error: value exceeds the largest integer",
+			dotEnv:    "MAX_SIZE=18446744073709551615",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg testConfig
+
+			err := BindFrom(&cfg, DotEnv(writeFile(t, t.TempDir(), ".env", tt.dotEnv)))
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			tt.expect(t, cfg)
+		})
+	}
+}
+
+func TestMapOf(t *testing.T) {
+	t.Run("error: not a pointer to a struct", func(t *testing.T) {
+		_, err := MapOf(testConfig{})
+		assert.Error(t, err)
+	})
+
+	t.Run("error: max exceeds the largest integer", func(t *testing.T) {
+		_, err := MapOf(&struct {
+			Size uint64 `env:"SIZE" max:"18446744073709551615"`
+		}{})
+		assert.Error(t, err)
+	})
+
+	t.Run("error: unsupported type", func(t *testing.T) {
+		_, err := MapOf(&struct {
+			Values map[string]string `env:"VALUES"`
+		}{})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,227 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package env
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/ingka-group/fastecho/stringutils"
+)
+
+// Section is a titled group of variables to document.
+type Section struct {
+	Title string
+	Map   Map
+}
+
+// WriteMarkdown documents the variables of the sections as Markdown tables. Default values of secrets
+// are redacted.
+func WriteMarkdown(w io.Writer, sections ...Section) error {
+	var b strings.Builder
+	for i, section := range sections {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+
+		fmt.Fprintf(&b, "## %s\n\n", section.Title)
+		b.WriteString("| Variable | Description | Type | Default | Required | Constraints |\n")
+		b.WriteString("|----------|-------------|------|---------|----------|-------------|\n")
+
+		for _, name := range slices.Sorted(maps.Keys(section.Map)) {
+			v := section.Map[name]
+
+			defaultValue := v.DefaultValue
+			if v.Secret && !stringutils.IsEmpty(defaultValue) {
+				defaultValue = Redacted
+			}
+
+			fmt.Fprintf(&b, "| `%s` | %s | %s | %s | %s | %s |\n",
+				name,
+				escapeMarkdown(v.Description),
+				v.Kind(),
+				code(defaultValue),
+				yesNo(v.IsRequired()),
+				escapeMarkdown(strings.Join(v.constraints(), ", ")),
+			)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteDotEnvExample documents the variables of the sections as a commented dotenv file. Variables are set
+// to their default value, and secrets are left empty.
+func WriteDotEnvExample(w io.Writer, sections ...Section) error {
+	var b strings.Builder
+	for i, section := range sections {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+
+		fmt.Fprintf(&b, "# --- %s ---\n", section.Title)
+
+		for _, name := range slices.Sorted(maps.Keys(section.Map)) {
+			v := section.Map[name]
+
+			b.WriteString("\n")
+			if !stringutils.IsEmpty(v.Description) {
+				fmt.Fprintf(&b, "# %s\n", v.Description)
+			}
+
+			details := append([]string{v.Kind()}, v.constraints()...)
+			if v.IsRequired() {
+				details = append(details, "required")
+			}
+			fmt.Fprintf(&b, "# (%s)\n", strings.Join(details, ", "))
+
+			value := v.DefaultValue
+			if v.Secret {
+				value = ""
+			}
+			fmt.Fprintf(&b, "%s=%s\n", name, value)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteJSONSchema documents the variables of the sections as a JSON schema of an object holding them.
+// All values are strings, as environment variables are, and default values of secrets are omitted.
+func WriteJSONSchema(w io.Writer, sections ...Section) error {
+	properties := make(map[string]any)
+	required := make([]string, 0)
+
+	for _, section := range sections {
+		for name, v := range section.Map {
+			property := map[string]any{
+				"type":        "string",
+				"description": strings.TrimSpace(fmt.Sprintf("%s (%s)", v.Description, v.Kind())),
+			}
+			if !stringutils.IsEmpty(v.DefaultValue) && !v.Secret {
+				property["default"] = v.DefaultValue
+			}
+			if len(v.OneOf) > 0 && !v.IsList {
+				property["enum"] = v.OneOf
+			}
+			if !stringutils.IsEmpty(v.Pattern) && !v.IsList {
+				property["pattern"] = "^(?:" + v.Pattern + ")$"
+			}
+			if v.IsURL {
+				property["format"] = "uri"
+			}
+			if v.Secret {
+				property["writeOnly"] = true
+			}
+
+			properties[name] = property
+			if v.IsRequired() {
+				required = append(required, name)
+			}
+		}
+	}
+
+	slices.Sort(required)
+
+	schema := map[string]any{
+		"$schema":    "https://json-schema.org/draft/2020-12/schema",
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(schema)
+}
+
+// Kind returns the kind of the variable, e.g. `integer` or `duration`.
+func (v *Var) Kind() string {
+	switch {
+	case v.IsInteger:
+		return "integer"
+	case v.IsBoolean:
+		return "boolean"
+	case v.IsFloat:
+		return "float"
+	case v.IsDuration:
+		return "duration"
+	case v.IsByteSize:
+		return "byte size"
+	case v.IsList:
+		return "list"
+	case v.IsURL:
+		return "URL"
+	default:
+		return "string"
+	}
+}
+
+// IsRequired returns whether the variable must be set, i.e. it is neither optional nor has a default value.
+func (v *Var) IsRequired() bool {
+	return !v.Optional && stringutils.IsEmpty(v.DefaultValue)
+}
+
+// constraints describes the constraints of the variable.
+func (v *Var) constraints() []string {
+	var constraints []string
+	if len(v.OneOf) > 0 {
+		constraints = append(constraints, "one of "+strings.Join(v.OneOf, "|"))
+	}
+	if !stringutils.IsEmpty(v.Min) {
+		constraints = append(constraints, "min "+v.Min)
+	}
+	if !stringutils.IsEmpty(v.Max) {
+		constraints = append(constraints, "max "+v.Max)
+	}
+	if !stringutils.IsEmpty(v.Pattern) {
+		constraints = append(constraints, "pattern "+v.Pattern)
+	}
+	if v.Secret {
+		constraints = append(constraints, "secret")
+	}
+
+	return constraints
+}
+
+// escapeMarkdown escapes the characters that would break a Markdown table cell.
+func escapeMarkdown(str string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(str)
+}
+
+// code formats a non-empty value as inline code.
+func code(str string) string {
+	if str == "" {
+		return ""
+	}
+
+	return "`" + str + "`"
+}
+
+// yesNo formats a boolean for humans.
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+
+	return "no"
+}
@@ -0,0 +1,107 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package env
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocs(t *testing.T) {
+	sections := []Section{
+		{
+			Title: "Database",
+			Map: Map{
+				"DB_PORT": {
+					Description:  "Port of the database",
+					DefaultValue: "5432",
+					IsInteger:    true,
+					Min:          "1",
+				},
+				"DB_PASSWORD": {
+					Description:  "Password | of the user",
+					DefaultValue: "changeme",
+					Secret:       true,
+				},
+				"DB_SSL_MODE": {
+					DefaultValue: "disable",
+					OneOf:        []string{"disable", "require"},
+				},
+				"DB_NAME": {},
+			},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		write  func(b *bytes.Buffer) error
+		expect string
+	}{
+		{
+			name: "ok: markdown",
+			write: func(b *bytes.Buffer) error {
+				return WriteMarkdown(b, sections...)
+			},
+			expect: "## Database\n\n" +
+				"| Variable | Description | Type | Default | Required | Constraints |\n" +
+				"|----------|-------------|------|---------|----------|-------------|\n" +
+				"| `DB_NAME` |  | string |  | yes |  |\n" +
+				"| `DB_PASSWORD` | Password \\| of the user | string | `******` | no | secret |\n" +
+				"| `DB_PORT` | Port of the database | integer | `5432` | no | min 1 |\n" +
+				"| `DB_SSL_MODE` |  | string | `disable` | no | one of disable\\|require |\n",
+		},
+		{
+			name: "ok: dotenv example",
+			write: func(b *bytes.Buffer) error {
+				return WriteDotEnvExample(b, sections...)
+			},
+			expect: "# --- Database ---\n" +
+				"\n# (string, required)\nDB_NAME=\n" +
+				"\n# Password | of the user\n# (string, secret)\nDB_PASSWORD=\n" +
+				"\n# Port of the database\n# (integer, min 1)\nDB_PORT=5432\n" +
+				"\n# (string, one of disable|require)\nDB_SSL_MODE=disable\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			require.NoError(t, tt.write(&b))
+			assert.Equal(t, tt.expect, b.String())
+		})
+	}
+
+	t.Run("ok: json schema", func(t *testing.T) {
+		var b bytes.Buffer
+		require.NoError(t, WriteJSONSchema(&b, sections...))
+
+		var schema struct {
+			Properties map[string]map[string]any `json:"properties"`
+			Required   []string                  `json:"required"`
+		}
+		require.NoError(t, json.Unmarshal(b.Bytes(), &schema))
+
+		assert.Equal(t, []string{"DB_NAME"}, schema.Required)
+		assert.Equal(t, "5432", schema.Properties["DB_PORT"]["default"])
+		assert.Equal(t, "Port of the database (integer)", schema.Properties["DB_PORT"]["description"])
+		assert.Equal(t, []any{"disable", "require"}, schema.Properties["DB_SSL_MODE"]["enum"])
+		assert.Equal(t, true, schema.Properties["DB_PASSWORD"]["writeOnly"])
+		assert.NotContains(t, schema.Properties["DB_PASSWORD"], "default")
+	})
+}
@@ -0,0 +1,143 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ingka-group/fastecho/stringutils"
+)
+
+const (
+	// EnvTypePlaceholder is replaced by the environment type in the names of dotenv files.
+	EnvTypePlaceholder = "{env}"
+
+	defaultEnvTypeVar = "ENV_TYPE"
+)
+
+// DefaultDotEnvNames are the names of the dotenv files loaded by default, in increasing order of precedence.
+var DefaultDotEnvNames = []string{".env", ".env." + EnvTypePlaceholder, ".env.local"}
+
+// DotEnvFiles describes dotenv files that are layered on top of each other, e.g. `.env`, then `.env.dev`,
+// then `.env.local`. Files later in the list override the ones before them.
+type DotEnvFiles struct {
+	// Dir is the directory in which the files are searched. Defaults to the working directory.
+	Dir string
+	// Names of the files in increasing order of precedence. The EnvTypePlaceholder is replaced by the
+	// environment type, and names containing it are skipped if there is none. Defaults to DefaultDotEnvNames.
+	Names []string
+	// EnvTypeVar is the variable holding the environment type. It is read from the environment of the
+	// process first, then from the files that don't depend on it. Defaults to `ENV_TYPE`.
+	EnvTypeVar string
+}
+
+// Sources returns a DotEnv Source for each existing file in order of precedence, i.e. the reverse order of
+// Names, so that they can be chained after the environment of the process. It also returns the paths of
+// the files that don't exist.
+func (f DotEnvFiles) Sources() ([]Source, []string, error) {
+	names := f.Names
+	if len(names) == 0 {
+		names = DefaultDotEnvNames
+	}
+
+	envType, err := f.envType(names)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var sources []Source
+	var missing []string
+	for _, name := range slices.Backward(names) {
+		if strings.Contains(name, EnvTypePlaceholder) {
+			if stringutils.IsEmpty(envType) {
+				continue
+			}
+			name = strings.ReplaceAll(name, EnvTypePlaceholder, envType)
+		}
+
+		path := filepath.Join(f.Dir, name)
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			missing = append(missing, path)
+			continue
+		}
+
+		sources = append(sources, DotEnv(path))
+	}
+
+	// Missing files are reported in the order they are declared
+	slices.Reverse(missing)
+
+	return sources, missing, nil
+}
+
+// envType returns the environment type from the environment of the process or, if it's not set there,
+// from the files that don't depend on it.
+func (f DotEnvFiles) envType(names []string) (string, error) {
+	envTypeVar := f.EnvTypeVar
+	if stringutils.IsEmpty(envTypeVar) {
+		envTypeVar = defaultEnvTypeVar
+	}
+
+	if envType := os.Getenv(envTypeVar); !stringutils.IsEmpty(envType) {
+		return envType, nil
+	}
+
+	var envType string
+	for _, name := range names {
+		if strings.Contains(name, EnvTypePlaceholder) {
+			continue
+		}
+
+		vars, err := DotEnv(filepath.Join(f.Dir, name)).Load()
+		if err != nil {
+			return "", err
+		}
+		if value := vars[envTypeVar]; !stringutils.IsEmpty(value) {
+			envType = value
+		}
+	}
+
+	return envType, nil
+}
+
+// Export sets the variables of the given sources in the environment of the process, so that they are
+// visible to libraries reading it directly. Variables that are already set are never overwritten, and
+// sources earlier in the list take precedence.
+func Export(sources ...Source) error {
+	for _, source := range sources {
+		vars, err := source.Load()
+		if err != nil {
+			return errors.Wrapf(err, "failed to load variables from `%s`", source.Name())
+		}
+
+		for name, value := range vars {
+			if _, ok := os.LookupEnv(name); ok {
+				continue
+			}
+
+			err = os.Setenv(name, value)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
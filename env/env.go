@@ -16,12 +16,15 @@
 
 import (
 	"fmt"
-	"os"
+	"math"
+	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/joho/godotenv"
 	"github.com/pkg/errors"
+	"go.uber.org/zap"
 
 	"github.com/ingka-group/fastecho/stringutils"
 )
@@ -32,28 +35,99 @@
 
 // Var describes an environment variable and its configuration.
 type Var struct {
-	Value        string
-	DefaultValue string
-	IsInteger    bool
-	IntValue     int
-	IsBoolean    bool
-	BooleanValue bool
-	OneOf        []string
-	Optional     bool // controls whether an env variable can be missing from the .env file but still declared
+	Description   string // describes the variable in the generated documentation
+	Value         string
+	DefaultValue  string
+	IsInteger     bool
+	IntValue      int
+	IsBoolean     bool
+	BooleanValue  bool
+	IsFloat       bool
+	FloatValue    float64
+	IsDuration    bool
+	DurationValue time.Duration
+	IsByteSize    bool // accepts sizes such as `512`, `10KB` or `1GiB`
+	ByteSizeValue int64
+	IsList        bool // accepts comma-separated values
+	ListValue     []string
+	IsURL         bool
+	URLValue      *url.URL
+	OneOf         []string
+	Min           string // lower bound of a numeric, duration or byte size value, or of the number of list items
+	Max           string // upper bound of a numeric, duration or byte size value, or of the number of list items
+	Pattern       string // regular expression the whole value, or each list item, must match
+	Optional      bool   // controls whether an env variable can be missing from the .env file but still declared
+	Secret        bool   // controls whether the value is redacted when the variable is rendered
+	Source        string // describes where the value comes from once it is set, e.g. `env` or `default`
 }
 
+const (
+	// fileSuffix is the suffix of a variable which points to a file containing the value of the variable.
+	fileSuffix = "_FILE"
+	// sourceDefault is the source of a variable that falls back to its default value.
+	sourceDefault = "default"
+)
+
 // SetEnv reads and sets the provided list of env vars based on the Map.
+// The variables are read from the environment of the process, which is complemented by the dotenv files
+// `.env`, `.env.<ENV_TYPE>` and `.env.local` in the working directory. Missing files are reported to the
+// global logger of zap, use SetEnvFromFiles to report them to another logger.
 func (m Map) SetEnv() error {
-	var messages []string
+	return m.SetEnvFromFiles(DotEnvFiles{}, zap.L())
+}
+
+// SetEnvFromFiles reads and sets the provided list of env vars based on the Map from the environment of the
+// process, complemented by the given dotenv files. The variables of the files are exported to the environment
+// of the process without overwriting the ones already set. Missing files are reported to the logger.
+func (m Map) SetEnvFromFiles(files DotEnvFiles, logger *zap.Logger) error {
+	sources, missing, err := files.Sources()
+	if err != nil {
+		return err
+	}
+
+	if len(missing) > 0 {
+		logger.Info("Configuration files don't exist. Don't worry, fastecho will read the environment variables.",
+			zap.Strings("files", missing),
+		)
+	}
+
+	err = m.SetEnvFrom(append([]Source{Environ()}, sources...)...)
+	if err != nil {
+		return err
+	}
+
+	return Export(sources...)
+}
 
-	// Overwrite passed env var values via env file
-	loadEnvFile(".env")
+// SetEnvFrom reads and sets the provided list of env vars based on the Map from the given sources.
+//
+// Sources are listed in order of precedence, i.e. a variable is read from the first source that
+// provides a non-empty value for it. If a source provides `<NAME>_FILE` instead of `<NAME>`, the value
+// is read from the file it points to.
+func (m Map) SetEnvFrom(sources ...Source) error {
+	loaded := make([]loadedSource, 0, len(sources))
+	for _, source := range sources {
+		vars, err := source.Load()
+		if err != nil {
+			return errors.Wrapf(err, "failed to load variables from `%s`", source.Name())
+		}
+		loaded = append(loaded, loadedSource{name: source.Name(), vars: vars})
+	}
+
+	var messages []string
 
 	for name, metadata := range m {
-		value := os.Getenv(name)
+		value, source, err := lookup(name, loaded)
+		if err != nil {
+			messages = append(messages,
+				fmt.Sprintf("variable `%s` could not be read: %s", name, err),
+			)
+		}
+
 		if stringutils.IsEmpty(value) {
 			if metadata.Optional || !stringutils.IsEmpty(metadata.DefaultValue) {
 				value = metadata.DefaultValue
+				source = sourceDefault
 			} else {
 				messages = append(messages,
 					fmt.Sprintf("variable `%s` is required", name),
@@ -61,51 +135,247 @@ func (m Map) SetEnv() error {
 			}
 		}
 
-		if len(metadata.OneOf) > 0 {
-			if !stringutils.ExistsInSlice(value, metadata.OneOf) {
-				messages = append(messages,
-					fmt.Sprintf("variable `%s` must be set to one of %v", name, metadata.OneOf),
-				)
-			}
+		// An optional variable without a value is not validated
+		if !stringutils.IsEmpty(value) || !metadata.Optional {
+			messages = append(messages, metadata.validate(name, value)...)
 		}
 
-		if metadata.IsInteger {
-			intValue, err := stringutils.ToInt(value)
-			if err != nil {
+		metadata.Value = value
+		metadata.Source = source
+		m[name] = metadata
+	}
+
+	if len(messages) > 0 {
+		return errors.New(strings.Join(messages, "; "))
+	}
+
+	return nil
+}
+
+// Prefixed returns a copy of the Map in which the names of the variables are prefixed, e.g. to declare the
+// variables of a second database with the same Map.
+func (m Map) Prefixed(prefix string) Map {
+	prefixed := make(Map, len(m))
+	for name, v := range m {
+		prefixed[prefix+name] = v.declaration()
+	}
+
+	return prefixed
+}
+
+// validate validates the value against the configuration of the variable and sets its typed values.
+// It returns the messages of all failed validations.
+func (v *Var) validate(name, value string) []string {
+	var messages []string
+
+	items := []string{value}
+	if v.IsList {
+		items = splitList(value)
+		v.ListValue = items
+	}
+
+	if len(v.OneOf) > 0 {
+		for _, item := range items {
+			if !stringutils.ExistsInSlice(item, v.OneOf) {
 				messages = append(messages,
-					fmt.Sprintf("variable `%s` requires an integer value", name),
+					fmt.Sprintf("variable `%s` must be set to one of %v", name, v.OneOf),
 				)
+				break
 			}
-			metadata.IntValue = intValue
 		}
+	}
 
-		if metadata.IsBoolean {
-			boolValue, err := strconv.ParseBool(value)
-			if err != nil {
-				messages = append(messages,
-					fmt.Sprintf("variable `%s` requires a boolean value", name),
-				)
+	if !stringutils.IsEmpty(v.Pattern) {
+		pattern, err := regexp.Compile("^(?:" + v.Pattern + ")$")
+		if err != nil {
+			messages = append(messages,
+				fmt.Sprintf("variable `%s` has an invalid pattern: %s", name, err),
+			)
+		} else {
+			for _, item := range items {
+				if !pattern.MatchString(item) {
+					messages = append(messages,
+						fmt.Sprintf("variable `%s` must match the pattern `%s`", name, v.Pattern),
+					)
+					break
+				}
 			}
+		}
+	}
 
-			metadata.BooleanValue = boolValue
+	if v.IsInteger {
+		intValue, err := stringutils.ToInt(value)
+		switch {
+		case errors.Is(err, strconv.ErrRange):
+			messages = append(messages,
+				fmt.Sprintf("variable `%s` exceeds the largest supported integer %d", name, math.MaxInt),
+			)
+		case err != nil:
+			messages = append(messages,
+				fmt.Sprintf("variable `%s` requires an integer value", name),
+			)
 		}
+		v.IntValue = intValue
+	}
 
-		metadata.Value = value
-		m[name] = metadata
+	if v.IsBoolean {
+		boolValue, err := strconv.ParseBool(value)
+		if err != nil {
+			messages = append(messages,
+				fmt.Sprintf("variable `%s` requires a boolean value", name),
+			)
+		}
+		v.BooleanValue = boolValue
 	}
 
-	if len(messages) > 0 {
-		return errors.New(strings.Join(messages, "; "))
+	if v.IsFloat {
+		floatValue, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			messages = append(messages,
+				fmt.Sprintf("variable `%s` requires a float value", name),
+			)
+		}
+		v.FloatValue = floatValue
 	}
 
-	return nil
+	if v.IsDuration {
+		durationValue, err := time.ParseDuration(value)
+		if err != nil {
+			messages = append(messages,
+				fmt.Sprintf("variable `%s` requires a duration value, e.g. `30s`", name),
+			)
+		}
+		v.DurationValue = durationValue
+	}
+
+	if v.IsByteSize {
+		byteSizeValue, err := stringutils.ToByteSize(value)
+		switch {
+		case errors.Is(err, strconv.ErrRange):
+			messages = append(messages,
+				fmt.Sprintf("variable `%s` exceeds the largest supported byte size %d", name, int64(math.MaxInt64)),
+			)
+		case err != nil:
+			messages = append(messages,
+				fmt.Sprintf("variable `%s` requires a byte size value, e.g. `10MB`", name),
+			)
+		}
+		v.ByteSizeValue = byteSizeValue
+	}
+
+	if v.IsURL {
+		urlValue, err := url.Parse(value)
+		if err != nil || stringutils.IsEmpty(urlValue.Scheme) {
+			messages = append(messages,
+				fmt.Sprintf("variable `%s` requires a URL value", name),
+			)
+			urlValue = nil
+		}
+		v.URLValue = urlValue
+	}
+
+	// Bounds are only checked once the value is known to be valid
+	if len(messages) == 0 && (!stringutils.IsEmpty(v.Min) || !stringutils.IsEmpty(v.Max)) {
+		messages = append(messages, v.checkBounds(name, value)...)
+	}
+
+	return messages
 }
 
-// loadEnvFile loads the variables of an env file. If it's not present, this step is skipped.
-func loadEnvFile(filename string) {
-	err := godotenv.Load(filename)
-	// Don't stop or fail if the .env file doesn't exist.
-	if errors.Is(err, os.ErrNotExist) {
-		fmt.Printf("'%s' configuration file doesn't exist. Don't worry, fastecho will read the environment variables.", filename)
+// checkBounds checks the value against the Min and Max bounds of the variable.
+func (v *Var) checkBounds(name, value string) []string {
+	n, err := v.numeric(value)
+	if err != nil {
+		return []string{fmt.Sprintf("variable `%s` cannot have bounds: %s", name, err)}
+	}
+
+	var messages []string
+
+	if !stringutils.IsEmpty(v.Min) {
+		minimum, err := v.bound(v.Min)
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("variable `%s` has an invalid min `%s`", name, v.Min))
+		} else if n < minimum {
+			messages = append(messages, fmt.Sprintf("variable `%s` must be at least `%s`", name, v.Min))
+		}
 	}
+
+	if !stringutils.IsEmpty(v.Max) {
+		maximum, err := v.bound(v.Max)
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("variable `%s` has an invalid max `%s`", name, v.Max))
+		} else if n > maximum {
+			messages = append(messages, fmt.Sprintf("variable `%s` must be at most `%s`", name, v.Max))
+		}
+	}
+
+	return messages
+}
+
+// numeric converts a value of the variable to a number that can be compared to the bounds.
+// For lists, this is the number of items.
+func (v *Var) numeric(value string) (float64, error) {
+	switch {
+	case v.IsInteger:
+		n, err := stringutils.ToInt(value)
+		return float64(n), err
+	case v.IsFloat:
+		return strconv.ParseFloat(value, 64)
+	case v.IsDuration:
+		d, err := time.ParseDuration(value)
+		return float64(d), err
+	case v.IsByteSize:
+		b, err := stringutils.ToByteSize(value)
+		return float64(b), err
+	case v.IsList:
+		return float64(len(splitList(value))), nil
+	default:
+		return 0, errors.New("only integer, float, duration, byte size and list variables support min and max")
+	}
+}
+
+// bound converts a bound of the variable to a number. The bounds of a list are integers.
+func (v *Var) bound(value string) (float64, error) {
+	if v.IsList {
+		n, err := stringutils.ToInt(value)
+		return float64(n), err
+	}
+
+	return v.numeric(value)
+}
+
+// splitList splits a comma-separated value into its trimmed, non-empty items.
+func splitList(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// loadedSource contains the variables loaded from a Source.
+type loadedSource struct {
+	name string
+	vars map[string]string
+}
+
+// lookup returns the value of the variable and the name of the source it comes from. The value is read
+// from the first source that provides it, either directly or through a file.
+func lookup(name string, loaded []loadedSource) (string, string, error) {
+	for _, source := range loaded {
+		if value := source.vars[name]; !stringutils.IsEmpty(value) {
+			return value, source.name, nil
+		}
+
+		if path := source.vars[name+fileSuffix]; !stringutils.IsEmpty(path) {
+			value, err := readValueFile(path)
+			return value, fmt.Sprintf("%s (%s)", source.name, path), err
+		}
+	}
+
+	return "", "", nil
 }
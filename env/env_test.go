@@ -0,0 +1,357 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package env
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFile writes a file to the given directory and returns its path.
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func TestMap_SetEnvFrom(t *testing.T) {
+	dir := t.TempDir()
+
+	dotEnv := writeFile(t, dir, ".env", "HOST=dotenv\nPORT=1234\n")
+	yamlFile := writeFile(t, dir, "config.yaml", "db:\n  host: yaml\n  port: 5432\nhosts:\n  - a\n  - b\n")
+	jsonFile := writeFile(t, dir, "config.json", `{"HOST": "json", "RATE": 0.5}`)
+	passwordFile := writeFile(t, dir, "password", "s3cr3t\n")
+
+	secrets := filepath.Join(dir, "secrets")
+	require.NoError(t, os.Mkdir(secrets, 0o700))
+	writeFile(t, secrets, "TOKEN", "from-dir\n")
+	writeFile(t, secrets, ".hidden", "ignored")
+
+	tests := []struct {
+		name      string
+		sources   []Source
+		given     Map
+		expect    map[string]string
+		expectErr bool
+	}{
+		{
+			name:    "ok: first source takes precedence",
+			sources: []Source{File(jsonFile), DotEnv(dotEnv)},
+			given: Map{
+				"HOST": {},
+				"PORT": {IsInteger: true},
+			},
+			expect: map[string]string{"HOST": "json", "PORT": "1234"},
+		},
+		{
+			name:    "ok: nested YAML keys and lists",
+			sources: []Source{File(yamlFile)},
+			given: Map{
+				"DB_HOST": {},
+				"DB_PORT": {IsInteger: true},
+				"HOSTS":   {},
+			},
+			expect: map[string]string{"DB_HOST": "yaml", "DB_PORT": "5432", "HOSTS": "a,b"},
+		},
+		{
+			name:    "ok: directory of files",
+			sources: []Source{Dir(secrets)},
+			given: Map{
+				"TOKEN":   {},
+				".hidden": {Optional: true},
+			},
+			expect: map[string]string{"TOKEN": "from-dir", ".hidden": ""},
+		},
+		{
+			name:    "ok: file indirection",
+			sources: []Source{DotEnv(writeFile(t, dir, ".env.file", "PASSWORD_FILE="+passwordFile))},
+			given: Map{
+				"PASSWORD": {},
+			},
+			expect: map[string]string{"PASSWORD": "s3cr3t"},
+		},
+		{
+			name:    "ok: missing files are skipped",
+			sources: []Source{DotEnv(filepath.Join(dir, "missing")), File(filepath.Join(dir, "missing.yaml")), Dir(filepath.Join(dir, "missing"))},
+			given: Map{
+				"HOST": {DefaultValue: "localhost"},
+			},
+			expect: map[string]string{"HOST": "localhost"},
+		},
+		{
+			name:    "error: required variable is missing",
+			sources: []Source{DotEnv(dotEnv)},
+			given: Map{
+				"MISSING": {},
+			},
+			expectErr: true,
+		},
+		{
+			name:    "error: invalid file",
+			sources: []Source{File(writeFile(t, dir, "invalid.yaml", "- not a map"))},
+			given: Map{
+				"HOST": {Optional: true},
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.given.SetEnvFrom(tt.sources...)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			for name, value := range tt.expect {
+				assert.Equal(t, value, tt.given[name].Value, name)
+			}
+		})
+	}
+}
+
+func TestMap_SetEnvFrom_Environ(t *testing.T) {
+	t.Setenv("FASTECHO_TEST_HOST", "env")
+
+	m := Map{
+		"FASTECHO_TEST_HOST": {},
+	}
+
+	err := m.SetEnvFrom(Environ(), DotEnv(writeFile(t, t.TempDir(), ".env", "FASTECHO_TEST_HOST=dotenv")))
+
+	require.NoError(t, err)
+	assert.Equal(t, "env", m["FASTECHO_TEST_HOST"].Value)
+}
+
+func TestMap_SetEnvFrom_Kinds(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name      string
+		value     string
+		given     *Var
+		expect    func(t *testing.T, v *Var)
+		expectErr bool
+	}{
+		{
+			name:  "ok: duration",
+			value: "1m30s",
+			given: &Var{IsDuration: true, Min: "1s", Max: "1h"},
+			expect: func(t *testing.T, v *Var) {
+				assert.Equal(t, 90*time.Second, v.DurationValue)
+			},
+		},
+		{
+			name:  "ok: float",
+			value: "0.25",
+			given: &Var{IsFloat: true, Min: "0", Max: "1"},
+			expect: func(t *testing.T, v *Var) {
+				assert.Equal(t, 0.25, v.FloatValue)
+			},
+		},
+		{
+			name:  "ok: list",
+			value: "a, b,,c",
+			given: &Var{IsList: true, OneOf: []string{"a", "b", "c"}, Max: "3"},
+			expect: func(t *testing.T, v *Var) {
+				assert.Equal(t, []string{"a", "b", "c"}, v.ListValue)
+			},
+		},
+		{
+			name:  "ok: URL",
+			value: "https://example.com/path",
+			given: &Var{IsURL: true},
+			expect: func(t *testing.T, v *Var) {
+				assert.Equal(t, "example.com", v.URLValue.Host)
+			},
+		},
+		{
+			name:  "ok: byte size",
+			value: "2MiB",
+			given: &Var{IsByteSize: true, Max: "10MB"},
+			expect: func(t *testing.T, v *Var) {
+				assert.Equal(t, int64(2<<20), v.ByteSizeValue)
+			},
+		},
+		{
+			name:  "ok: pattern",
+			value: "eu-west-1",
+			given: &Var{Pattern: `[a-z]+-[a-z]+-\d`},
+			expect: func(t *testing.T, v *Var) {
+				assert.Equal(t, "eu-west-1", v.Value)
+			},
+		},
+		{
+			name:  "ok: optional without value is not validated",
+			value: "",
+			given: &Var{IsInteger: true, Optional: true},
+			expect: func(t *testing.T, v *Var) {
+				assert.Equal(t, "", v.Value)
+			},
+		},
+		{
+			name:      "error: invalid duration",
+			value:     "10",
+			given:     &Var{IsDuration: true},
+			expectErr: true,
+		},
+		{
+			name:      "error: byte size exceeds the largest size",
+			value:     "10000000TB",
+			given:     &Var{IsByteSize: true},
+			expectErr: true,
+		},
+		{
+			name:      "error: below min",
+			value:     "5",
+			given:     &Var{IsInteger: true, Min: "10"},
+			expectErr: true,
+		},
+		{
+			name:      "error: too many list items",
+			value:     "a,b,c",
+			given:     &Var{IsList: true, Max: "2"},
+			expectErr: true,
+		},
+		{
+			name:      "error: URL without scheme",
+			value:     "example.com",
+			given:     &Var{IsURL: true},
+			expectErr: true,
+		},
+		{
+			name:      "error: partial pattern match",
+			value:     "eu-west-1a",
+			given:     &Var{Pattern: `[a-z]+-[a-z]+-\d`},
+			expectErr: true,
+		},
+		{
+			name:      "error: bounds on a string",
+			value:     "abc",
+			given:     &Var{Min: "1"},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := Map{"VALUE": tt.given}
+
+			err := m.SetEnvFrom(DotEnv(writeFile(t, dir, ".env", "VALUE="+tt.value)))
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			tt.expect(t, m["VALUE"])
+		})
+	}
+}
+
+func TestMap_Render(t *testing.T) {
+	m := Map{
+		"HOST":     {DefaultValue: "localhost"},
+		"PASSWORD": {Secret: true},
+		"TOKEN":    {Secret: true, Optional: true},
+	}
+
+	err := m.SetEnvFrom(DotEnv(writeFile(t, t.TempDir(), ".env", "PASSWORD=s3cr3t")))
+	require.NoError(t, err)
+
+	assert.NotContains(t, m.String(), "s3cr3t")
+	assert.Contains(t, m.String(), "HOST=localhost (default)\n")
+
+	data, err := json.Marshal(m)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "s3cr3t")
+
+	// variables marshalled on their own are redacted too
+	for _, v := range []any{m["PASSWORD"], *m["PASSWORD"]} {
+		data, err = json.Marshal(v)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "s3cr3t")
+		assert.Contains(t, string(data), `"value":"******"`)
+	}
+
+	rendered := m.Render()
+	assert.Equal(t, Redacted, rendered["PASSWORD"].Value)
+	assert.Equal(t, "", rendered["TOKEN"].Value)
+}
+
+func TestDotEnvFiles_Sources(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, ".env", "ENV_TYPE=dev\nHOST=base\nPORT=1\nNAME=base\n")
+	writeFile(t, dir, ".env.dev", "HOST=dev\nPORT=2\n")
+	writeFile(t, dir, ".env.local", "PORT=3\n")
+
+	tests := []struct {
+		name          string
+		processEnv    string
+		expect        map[string]string
+		expectMissing []string
+	}{
+		{
+			name:   "ok: env type from the base file",
+			expect: map[string]string{"NAME": "base", "HOST": "dev", "PORT": "3"},
+		},
+		{
+			name:          "ok: env type from the process",
+			processEnv:    "prod",
+			expect:        map[string]string{"NAME": "base", "HOST": "base", "PORT": "3"},
+			expectMissing: []string{filepath.Join(dir, ".env.prod")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ENV_TYPE", tt.processEnv)
+
+			sources, missing, err := DotEnvFiles{Dir: dir}.Sources()
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectMissing, missing)
+
+			m := Map{"NAME": {}, "HOST": {}, "PORT": {}}
+			require.NoError(t, m.SetEnvFrom(sources...))
+
+			for name, value := range tt.expect {
+				assert.Equal(t, value, m[name].Value, name)
+			}
+		})
+	}
+}
+
+func TestExport(t *testing.T) {
+	t.Setenv("FASTECHO_TEST_SET", "process")
+	t.Setenv("FASTECHO_TEST_UNSET", "")
+	require.NoError(t, os.Unsetenv("FASTECHO_TEST_UNSET"))
+
+	err := Export(DotEnv(writeFile(t, t.TempDir(), ".env", "FASTECHO_TEST_SET=file\nFASTECHO_TEST_UNSET=file\n")))
+
+	require.NoError(t, err)
+	assert.Equal(t, "process", os.Getenv("FASTECHO_TEST_SET"))
+	assert.Equal(t, "file", os.Getenv("FASTECHO_TEST_UNSET"))
+}
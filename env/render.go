@@ -0,0 +1,89 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package env
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Redacted replaces the value of secret variables when they are rendered.
+const Redacted = "******"
+
+// RenderedVar is the rendering of a variable, with its value redacted if it is a secret.
+type RenderedVar struct {
+	Value  string `json:"value"`
+	Source string `json:"source,omitempty"`
+	Secret bool   `json:"secret,omitempty"`
+}
+
+// Render returns the variables of the Map, with the values of secrets redacted.
+func (m Map) Render() map[string]RenderedVar {
+	rendered := make(map[string]RenderedVar, len(m))
+	for name, v := range m {
+		rendered[name] = v.render()
+	}
+
+	return rendered
+}
+
+// String renders the variables of the Map sorted by name, one per line, with the values of secrets redacted.
+func (m Map) String() string {
+	var b strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(m)) {
+		b.WriteString(name)
+		b.WriteString("=")
+		b.WriteString(m[name].redactedValue())
+		if source := m[name].Source; source != "" {
+			b.WriteString(" (")
+			b.WriteString(source)
+			b.WriteString(")")
+		}
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// MarshalJSON renders the variables of the Map as JSON, with the values of secrets redacted.
+func (m Map) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.Render())
+}
+
+// MarshalJSON renders the variable as JSON, with its value redacted if it is a secret.
+// It has a value receiver, so that copies of the variable, e.g. returned by Watcher.Var, are redacted too.
+func (v Var) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.render())
+}
+
+// render returns the rendering of the variable.
+func (v *Var) render() RenderedVar {
+	return RenderedVar{
+		Value:  v.redactedValue(),
+		Source: v.Source,
+		Secret: v.Secret,
+	}
+}
+
+// redactedValue returns the value of the variable, or Redacted if it's a secret with a value.
+func (v *Var) redactedValue() string {
+	if v.Secret && v.Value != "" {
+		return Redacted
+	}
+
+	return v.Value
+}
@@ -0,0 +1,253 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package env
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Source provides the values of environment variables, e.g. from the process environment or from files.
+type Source interface {
+	// Name describes the source, e.g. the path of a file.
+	Name() string
+	// Load returns the variables of the source by name.
+	Load() (map[string]string, error)
+}
+
+// Environ returns a Source that reads the environment of the process.
+func Environ() Source {
+	return environSource{}
+}
+
+type environSource struct{}
+
+func (environSource) Name() string {
+	return "env"
+}
+
+func (environSource) Load() (map[string]string, error) {
+	vars := make(map[string]string)
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		vars[name] = value
+	}
+
+	return vars, nil
+}
+
+// Vars returns a Source that provides the given variables, e.g. a snapshot of the environment of the process.
+func Vars(name string, vars map[string]string) Source {
+	return varsSource{name: name, vars: vars}
+}
+
+type varsSource struct {
+	name string
+	vars map[string]string
+}
+
+func (s varsSource) Name() string {
+	return s.name
+}
+
+func (s varsSource) Load() (map[string]string, error) {
+	return maps.Clone(s.vars), nil
+}
+
+// fileBackedSource is a Source that reads a file or a directory, which can be watched for changes.
+type fileBackedSource interface {
+	Source
+	watchPath() string
+}
+
+// DotEnv returns a Source that reads a dotenv file. A missing file provides no variables.
+func DotEnv(path string) Source {
+	return dotEnvSource{path: path}
+}
+
+type dotEnvSource struct {
+	path string
+}
+
+func (s dotEnvSource) Name() string {
+	return s.path
+}
+
+func (s dotEnvSource) watchPath() string {
+	return s.path
+}
+
+func (s dotEnvSource) Load() (map[string]string, error) {
+	vars, err := godotenv.Read(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read `%s`", s.path)
+	}
+
+	return vars, nil
+}
+
+// File returns a Source that reads a YAML or JSON file. A missing file provides no variables.
+//
+// Nested keys are joined with an underscore and all keys are upper-cased, e.g. `db: {host: localhost}`
+// provides `DB_HOST`. Lists are joined with a comma.
+func File(path string) Source {
+	return fileSource{path: path}
+}
+
+type fileSource struct {
+	path string
+}
+
+func (s fileSource) Name() string {
+	return s.path
+}
+
+func (s fileSource) watchPath() string {
+	return s.path
+}
+
+func (s fileSource) Load() (map[string]string, error) {
+	content, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// YAML is a superset of JSON, so both can be decoded by the YAML decoder
+	var tree map[string]any
+	err = yaml.Unmarshal(content, &tree)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse `%s`", s.path)
+	}
+
+	vars := make(map[string]string)
+	flatten("", tree, vars)
+
+	return vars, nil
+}
+
+// flatten adds the values of the tree to vars, joining nested keys with an underscore.
+func flatten(prefix string, tree map[string]any, vars map[string]string) {
+	for key, value := range tree {
+		name := strings.ToUpper(key)
+		if prefix != "" {
+			name = prefix + "_" + name
+		}
+
+		if nested, ok := value.(map[string]any); ok {
+			flatten(name, nested, vars)
+			continue
+		}
+
+		vars[name] = toString(value)
+	}
+}
+
+// toString formats a decoded YAML value as the value of an environment variable.
+func toString(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, toString(item))
+		}
+		return strings.Join(items, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Dir returns a Source that reads a directory of files, where the name of each file is the name of the
+// variable and its content is the value, e.g. secrets mounted by Kubernetes. A missing directory provides
+// no variables. Hidden files and subdirectories are ignored.
+func Dir(path string) Source {
+	return dirSource{path: path}
+}
+
+type dirSource struct {
+	path string
+}
+
+func (s dirSource) Name() string {
+	return s.path
+}
+
+func (s dirSource) watchPath() string {
+	return s.path
+}
+
+func (s dirSource) Load() (map[string]string, error) {
+	entries, err := os.ReadDir(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	vars := make(map[string]string)
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		// Follow symlinks, as used by Kubernetes for mounted secrets
+		path := filepath.Join(s.path, entry.Name())
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.Mode().IsRegular() {
+			continue
+		}
+
+		value, err := readValueFile(path)
+		if err != nil {
+			return nil, err
+		}
+		vars[entry.Name()] = value
+	}
+
+	return vars, nil
+}
+
+// readValueFile reads the value of a variable from a file, trimming the trailing line break.
+func readValueFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
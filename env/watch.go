@@ -0,0 +1,268 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package env
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// defaultWatchInterval defines how often the files of the sources are checked for changes by default.
+const defaultWatchInterval = 5 * time.Second
+
+// WatchOpts define configuration options for a Watcher.
+type WatchOpts struct {
+	// Interval defines how often the files of the sources are checked for changes. Defaults to 5s.
+	Interval time.Duration
+	// Signals trigger a reload when received by the process. Defaults to SIGHUP.
+	Signals []os.Signal
+	// Logger reports the reloads, their changes and rejected reloads. Defaults to zap.L().
+	Logger *zap.Logger
+}
+
+// Subscriber is notified of the previous and the new value of a variable when it changes.
+type Subscriber func(previous, current Var)
+
+// Watcher reloads the variables of a Map from its sources when a file of the sources changes or when the
+// process receives SIGHUP.
+//
+// A reload re-runs the validation of all the variables and is rejected as a whole when any of them is
+// invalid. Otherwise, the values are swapped at once and the subscribers of the changed variables are
+// notified. The Map given to the watcher keeps the values it had when the watcher was created, the
+// current values are returned by Map and Var.
+type Watcher struct {
+	declared Map
+	sources  []Source
+	opts     WatchOpts
+
+	current atomic.Pointer[Map]
+
+	mu          sync.Mutex // serializes the reloads and guards the subscribers
+	subscribers map[string][]Subscriber
+}
+
+// NewWatcher creates a Watcher of the given Map, the values of which are already set, and of the sources
+// it is read from, in order of precedence.
+func NewWatcher(m Map, sources []Source, opts WatchOpts) *Watcher {
+	if opts.Interval <= 0 {
+		opts.Interval = defaultWatchInterval
+	}
+	if len(opts.Signals) == 0 {
+		opts.Signals = []os.Signal{syscall.SIGHUP}
+	}
+	if opts.Logger == nil {
+		opts.Logger = zap.L()
+	}
+
+	w := &Watcher{
+		declared:    m,
+		sources:     sources,
+		opts:        opts,
+		subscribers: make(map[string][]Subscriber),
+	}
+
+	snapshot := make(Map, len(m))
+	for name, v := range m {
+		copied := *v
+		snapshot[name] = &copied
+	}
+	w.current.Store(&snapshot)
+
+	return w
+}
+
+// Map returns the current values of the variables. It must not be modified.
+func (w *Watcher) Map() Map {
+	return *w.current.Load()
+}
+
+// Var returns the current value of a variable.
+func (w *Watcher) Var(name string) (Var, bool) {
+	v, ok := w.Map()[name]
+	if !ok {
+		return Var{}, false
+	}
+
+	return *v, true
+}
+
+// Subscribe registers a function that is notified when the value of the given variable changes.
+func (w *Watcher) Subscribe(name string, fn Subscriber) error {
+	if _, ok := w.declared[name]; !ok {
+		return errors.Errorf("variable `%s` is not declared", name)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.subscribers[name] = append(w.subscribers[name], fn)
+
+	return nil
+}
+
+// Reload reads the variables from the sources again. When any variable is invalid, the reload is rejected
+// and the current values are kept.
+func (w *Watcher) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	reloaded := make(Map, len(w.declared))
+	for name, v := range w.declared {
+		reloaded[name] = v.declaration()
+	}
+
+	err := reloaded.SetEnvFrom(w.sources...)
+	if err != nil {
+		w.opts.Logger.Error("Configuration reload rejected", zap.Error(err))
+		return err
+	}
+
+	previous := w.Map()
+	changed := changes(previous, reloaded)
+	if len(changed) == 0 {
+		w.opts.Logger.Debug("Configuration reloaded without changes")
+		return nil
+	}
+
+	w.current.Store(&reloaded)
+
+	diff := make([]string, 0, len(changed))
+	for _, name := range changed {
+		diff = append(diff, fmt.Sprintf("%s: %q -> %q", name, previous[name].redactedValue(), reloaded[name].redactedValue()))
+	}
+	w.opts.Logger.Info("Configuration reloaded", zap.Strings("changes", diff))
+
+	for _, name := range changed {
+		for _, fn := range w.subscribers[name] {
+			fn(*previous[name], *reloaded[name])
+		}
+	}
+
+	return nil
+}
+
+// Start watches the files of the sources and the signals in the background until the context is done.
+func (w *Watcher) Start(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, w.opts.Signals...)
+
+	fingerprint := w.fingerprint()
+
+	go func() {
+		defer signal.Stop(signals)
+
+		ticker := time.NewTicker(w.opts.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-signals:
+				w.opts.Logger.Info("Reloading configuration", zap.String("signal", sig.String()))
+				_ = w.Reload()
+			case <-ticker.C:
+				current := w.fingerprint()
+				if current == fingerprint {
+					continue
+				}
+
+				fingerprint = current
+				w.opts.Logger.Info("Reloading configuration as its files changed")
+				_ = w.Reload()
+			}
+		}
+	}()
+}
+
+// fingerprint describes the state of the files of the sources, so that changes can be detected.
+// Directories are described by the files they contain.
+func (w *Watcher) fingerprint() string {
+	var fingerprint string
+
+	for _, source := range w.sources {
+		s, ok := source.(fileBackedSource)
+		if !ok {
+			continue
+		}
+
+		fingerprint += describeFile(s.watchPath())
+
+		entries, err := os.ReadDir(s.watchPath())
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			fingerprint += describeFile(filepath.Join(s.watchPath(), entry.Name()))
+		}
+	}
+
+	return fingerprint
+}
+
+// describeFile describes the modification time and size of a file, following symlinks.
+func describeFile(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return path + ":missing;"
+	}
+
+	return fmt.Sprintf("%s:%d:%d;", path, info.ModTime().UnixNano(), info.Size())
+}
+
+// changes returns the sorted names of the variables the value of which differs between the maps.
+func changes(previous, current Map) []string {
+	var changed []string
+	for _, name := range slices.Sorted(maps.Keys(current)) {
+		if p, ok := previous[name]; !ok || p.Value != current[name].Value {
+			changed = append(changed, name)
+		}
+	}
+
+	return changed
+}
+
+// declaration returns a copy of the variable without its values.
+func (v *Var) declaration() *Var {
+	return &Var{
+		Description:  v.Description,
+		DefaultValue: v.DefaultValue,
+		IsInteger:    v.IsInteger,
+		IsBoolean:    v.IsBoolean,
+		IsFloat:      v.IsFloat,
+		IsDuration:   v.IsDuration,
+		IsByteSize:   v.IsByteSize,
+		IsList:       v.IsList,
+		IsURL:        v.IsURL,
+		OneOf:        v.OneOf,
+		Min:          v.Min,
+		Max:          v.Max,
+		Pattern:      v.Pattern,
+		Optional:     v.Optional,
+		Secret:       v.Secret,
+	}
+}
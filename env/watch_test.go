@@ -0,0 +1,134 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package env
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestWatcher_Reload(t *testing.T) {
+	tests := []struct {
+		name         string
+		dotEnv       string
+		expectLimit  int
+		expectNotify bool
+		expectErr    bool
+	}{
+		{
+			name:         "ok: changed value",
+			dotEnv:       "RATE_LIMIT=20\nLOG_LEVEL=info\n",
+			expectLimit:  20,
+			expectNotify: true,
+		},
+		{
+			name:        "ok: unchanged value",
+			dotEnv:      "RATE_LIMIT=10\nLOG_LEVEL=info\n",
+			expectLimit: 10,
+		},
+		{
+			name:        "error: invalid value is rejected",
+			dotEnv:      "RATE_LIMIT=many\nLOG_LEVEL=info\n",
+			expectLimit: 10,
+			expectErr:   true,
+		},
+		{
+			name:        "error: missing required value is rejected",
+			dotEnv:      "RATE_LIMIT=20\n",
+			expectLimit: 10,
+			expectErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFile(t, t.TempDir(), ".env", "RATE_LIMIT=10\nLOG_LEVEL=info\n")
+			sources := []Source{DotEnv(path)}
+
+			m := Map{
+				"RATE_LIMIT": {IsInteger: true},
+				"LOG_LEVEL":  {OneOf: []string{"debug", "info"}},
+			}
+			require.NoError(t, m.SetEnvFrom(sources...))
+
+			w := NewWatcher(m, sources, WatchOpts{Logger: zap.NewNop()})
+
+			var notified []int
+			require.NoError(t, w.Subscribe("RATE_LIMIT", func(previous, current Var) {
+				notified = append(notified, previous.IntValue, current.IntValue)
+			}))
+
+			writeFile(t, filepath.Dir(path), ".env", tt.dotEnv)
+
+			err := w.Reload()
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			v, ok := w.Var("RATE_LIMIT")
+			require.True(t, ok)
+			assert.Equal(t, tt.expectLimit, v.IntValue)
+			assert.Equal(t, 10, m["RATE_LIMIT"].IntValue)
+
+			if tt.expectNotify {
+				assert.Equal(t, []int{10, tt.expectLimit}, notified)
+			} else {
+				assert.Empty(t, notified)
+			}
+		})
+	}
+
+	t.Run("error: subscribe to undeclared variable", func(t *testing.T) {
+		w := NewWatcher(Map{}, nil, WatchOpts{Logger: zap.NewNop()})
+		assert.Error(t, w.Subscribe("UNKNOWN", func(_, _ Var) {}))
+	})
+}
+
+func TestWatcher_Start(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, ".env", "FEATURE=false\n")
+	sources := []Source{DotEnv(path)}
+
+	m := Map{"FEATURE": {IsBoolean: true}}
+	require.NoError(t, m.SetEnvFrom(sources...))
+
+	w := NewWatcher(m, sources, WatchOpts{Interval: 10 * time.Millisecond, Logger: zap.NewNop()})
+
+	changed := make(chan bool, 1)
+	require.NoError(t, w.Subscribe("FEATURE", func(_, current Var) {
+		changed <- current.BooleanValue
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w.Start(ctx)
+
+	writeFile(t, dir, ".env", "FEATURE=true\n")
+
+	select {
+	case v := <-changed:
+		assert.True(t, v)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the change was not picked up")
+	}
+}
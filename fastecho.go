@@ -16,13 +16,15 @@
 
 import (
 	gocontext "context"
+	"crypto/tls"
 	"errors"
-	"fmt"
 	"maps"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
-	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/google/uuid"
@@ -30,6 +32,7 @@
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/propagation"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
@@ -38,6 +41,7 @@
 	"github.com/ingka-group/fastecho/echozap"
 	"github.com/ingka-group/fastecho/env"
 	"github.com/ingka-group/fastecho/errs"
+	"github.com/ingka-group/fastecho/health"
 	"github.com/ingka-group/fastecho/otel"
 	"github.com/ingka-group/fastecho/router"
 	"github.com/ingka-group/fastecho/stringutils"
@@ -51,43 +55,90 @@
 	swaggerUITitle  = "SWAGGER_UI_TITLE"
 	swaggerJSONPath = "SWAGGER_JSON_PATH"
 
+	shutdownTimeout = "SHUTDOWN_TIMEOUT"
+
+	adminPort = "ADMIN_PORT"
+
 	localEnv = "local"
 	devEnv   = "dev"
 	testEnv  = "test"
 	prodEnv  = "prod"
 )
 
-var (
-	// Environment variables for fastecho to operate.
-	envs = env.Map{
+// newEnvs returns the environment variables for fastecho to operate.
+func newEnvs() env.Map {
+	return env.Map{
 		hostname: {
+			Description:  "Host the server listens on",
 			DefaultValue: "localhost",
 		},
 		port: {
+			Description:  "Port the server listens on",
 			DefaultValue: "8080",
 			IsInteger:    true,
 		},
 		envType: {
+			Description:  "Environment the service runs in, which selects the `.env.<ENV_TYPE>` file",
 			DefaultValue: devEnv,
 			OneOf:        []string{localEnv, devEnv, testEnv, prodEnv},
 		},
 		swaggerJSONPath: {
-			// Defines the path to the swagger.json file on the server. This is used by the swagger UI.
+			Description:  "Path to the swagger.json file on the server, used by the swagger UI",
 			DefaultValue: "/swagger/swagger.json",
 		},
 		swaggerUITitle: {
+			Description:  "Title of the swagger UI",
 			DefaultValue: "FastEcho Service",
 		},
+		shutdownTimeout: {
+			Description:  "How long in-flight requests are given to complete during a graceful shutdown",
+			DefaultValue: "10s",
+			IsDuration:   true,
+		},
+		adminPort: {
+			Description: "Port of the admin server hosting metrics, health checks and swagger. When empty, they are hosted by the main server",
+			Optional:    true,
+			IsInteger:   true,
+		},
 	}
-)
+}
 
 // server is a wrapper around Echo.
 type server struct {
 	Echo           *echo.Echo
+	AdminEcho      *echo.Echo
 	Router         *router.Router
 	Logger         *zap.Logger
 	Tracer         *trace.Tracer
 	TracerProvider *sdktrace.TracerProvider
+	Propagator     propagation.TextMapPropagator
+	Registerer     prometheus.Registerer
+	Gatherer       prometheus.Gatherer
+
+	envs       env.Map
+	envWatcher *env.Watcher // reloads the envs at runtime, if enabled
+	tlsConfig  *tls.Config
+	adminAddr  string
+
+	registered *serverRegisterer    // the collectors of fastecho, unregistered on shutdown
+	dbMetrics  *prometheus.Registry // the metrics of the databases, if the metrics are enabled
+
+	shutdownOpts  ShutdownOpts
+	shutdownState *health.ShutdownState
+	drainDelay    time.Duration
+	shutdownOnce  sync.Once
+	shutdownErr   error
+
+	hooksMu sync.Mutex
+	hooks   []lifecycleHook
+	started int // number of hooks started, which are stopped in reverse order
+}
+
+// lifecycleHook pairs an OnStart hook with the OnStop hook at the same position, which is only invoked
+// once the OnStart hook succeeded. Either of them may be nil.
+type lifecycleHook struct {
+	start Hook
+	stop  Hook
 }
 
 type FastEcho struct {
@@ -95,56 +146,151 @@ type FastEcho struct {
 }
 
 // Run starts a new instance of fastecho.
+// When the process is started with `--print-env-docs[=markdown|dotenv|jsonschema]`, Run prints the
+// documentation of the environment variables instead and returns without starting the server.
+// Likewise, when it is started with the `migrate` subcommand, e.g. `migrate status`, Run manages the
+// migrations of the database with the Config.DBOptions. See Migrate.
 func Run(cfg *Config) error {
-	s, err := newServer(cfg)
-	if err != nil {
-		return err
+	if format, ok := envDocsFormat(os.Args[1:]); ok {
+		return PrintEnvDocs(os.Stdout, cfg, format)
 	}
 
-	// Allow custom Echo configuration
-	if cfg.EchoFn != nil {
-		err = cfg.EchoFn(s.Echo)
-		if err != nil {
-			return err
+	if len(os.Args) > 1 && os.Args[1] == migrateCommand {
+		var opts []DBOption
+		if cfg != nil {
+			// the variables are read as by the server, the DBOptions may still override the sources
+			sources, _, _, err := cfg.Opts.Env.sources()
+			if err != nil {
+				return err
+			}
+			opts = append([]DBOption{WithSources(sources...)}, cfg.DBOptions...)
 		}
+		return Migrate(gocontext.Background(), os.Args[2:], opts...)
 	}
 
-	err = s.Router.Setup()
+	fe, err := Initialize(cfg)
 	if err != nil {
 		return err
 	}
 
-	s.Router.PrintRoutes(s.Echo)
-
 	// Run it!
-	return s.run(envs[hostname].Value, envs[port].Value)
+	return fe.server.run(fe.server.envs[hostname].Value, fe.server.envs[port].Value)
 }
 
 // Initialize sets up a new instance of FastEcho and returns a prepared FastEcho type, but does not
 // boot the server.
 func Initialize(cfg *Config) (*FastEcho, error) {
+	// If no configuration is passed,
+	// the service should still run with default values
+	if cfg == nil {
+		cfg = &Config{}
+	}
+
 	s, err := newServer(cfg)
 	if err != nil {
 		return nil, err
 	}
 
+	err = s.prepare(cfg)
+	if err != nil {
+		s.unregisterMetrics()
+		return nil, err
+	}
+
 	return &FastEcho{server: s}, nil
 }
 
+// Registerer returns the Prometheus registerer of the server, to which the collectors of the service can be
+// registered. See MetricsOpts.Registry.
+func (fe *FastEcho) Registerer() prometheus.Registerer {
+	return fe.server.Registerer
+}
+
+// Gatherer returns the Prometheus gatherer of the server, which is served by the metrics endpoint along with the
+// metrics of the databases.
+func (fe *FastEcho) Gatherer() prometheus.Gatherer {
+	return fe.server.Gatherer
+}
+
 // Handler returns the Echo handler for the defined FastEcho server.
 func (fe *FastEcho) Handler() http.Handler {
 	return fe.server.Echo
 }
 
-// Shutdown cleanly shuts down the server and any tracing providers.
-func (fe *FastEcho) Shutdown(ctx gocontext.Context) error {
-	if fe.server.TracerProvider != nil {
-		_ = fe.server.TracerProvider.Shutdown(ctx)
+// AdminHandler returns the Echo handler of the admin server, or nil if the admin server is disabled.
+func (fe *FastEcho) AdminHandler() http.Handler {
+	if fe.server.AdminEcho == nil {
+		return nil
+	}
+
+	return fe.server.AdminEcho
+}
+
+// Start binds the server to the configured host and port, invokes the OnStart hooks and serves
+// requests in the background. It returns once the listener is bound. The server is shut down
+// gracefully when the given context is cancelled.
+func (fe *FastEcho) Start(ctx gocontext.Context) error {
+	ln, err := fe.server.listen(fe.server.envs[hostname].Value, fe.server.envs[port].Value)
+	if err != nil {
+		return err
+	}
+
+	errCh, err := fe.server.startServing(ctx, ln)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			shutdownCtx, cancel := gocontext.WithTimeout(gocontext.Background(), fe.server.drainDelay+fe.server.shutdownOpts.Timeout)
+			defer cancel()
+
+			_ = fe.Shutdown(shutdownCtx)
+		case err := <-errCh:
+			if err != nil {
+				fe.server.stopUnexpectedly(err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Serve invokes the OnStart hooks and serves requests on the given listener. It blocks until the
+// server is shut down with Shutdown, in which case it returns nil, or until serving fails.
+func (fe *FastEcho) Serve(ln net.Listener) error {
+	errCh, err := fe.server.startServing(gocontext.Background(), ln)
+	if err != nil {
+		return err
+	}
+
+	err = <-errCh
+	if err != nil {
+		fe.server.stopUnexpectedly(err)
+	}
+
+	return err
+}
+
+// Addr returns the address the server listens on, or nil if it has not been started.
+// This is useful to find out the actual port when the server is bound to port 0.
+func (fe *FastEcho) Addr() net.Addr {
+	return fe.server.Echo.ListenerAddr()
+}
+
+// AdminAddr returns the address the admin server listens on, or nil if it is disabled or has not been started.
+func (fe *FastEcho) AdminAddr() net.Addr {
+	if fe.server.AdminEcho == nil {
+		return nil
 	}
-	// Clean up global variables after shutdown
-	defer func() { prometheus.DefaultRegisterer = prometheus.NewRegistry() }()
 
-	return fe.server.Echo.Shutdown(ctx)
+	return fe.server.AdminEcho.ListenerAddr()
+}
+
+// Shutdown cleanly shuts down the server and any tracing providers.
+func (fe *FastEcho) Shutdown(ctx gocontext.Context) error {
+	return fe.server.shutdown(ctx)
 }
 
 func newServer(cfg *Config) (*server, error) {
@@ -159,6 +305,7 @@ func newServer(cfg *Config) (*server, error) {
 
 	err := s.setup(cfg)
 	if err != nil {
+		s.unregisterMetrics()
 		return nil, err
 	}
 
@@ -171,6 +318,8 @@ func (s *server) setup(cfg *Config) error {
 
 	// set up echo
 	s.Echo = echo.New()
+	s.shutdownState = health.NewShutdownState()
+	s.drainDelay = cfg.Opts.HealthChecks.DrainDelay
 
 	// config the service
 	err = s.config(cfg)
@@ -178,18 +327,34 @@ func (s *server) setup(cfg *Config) error {
 		return err
 	}
 
+	// set up the admin server, if enabled
+	if !stringutils.IsEmpty(s.adminAddr) {
+		s.AdminEcho = echo.New()
+		s.AdminEcho.HideBanner = true
+		s.AdminEcho.Use(middleware.Recover())
+	}
+
 	// set up middlewares
-	s.middlewares(cfg)
+	err = s.middlewares(cfg)
+	if err != nil {
+		return err
+	}
 
 	fastechoRouter, err := router.NewRouter(
 		router.Config{
 			Echo:             s.Echo,
+			AdminEcho:        s.AdminEcho,
 			Routes:           cfg.Routes,
 			SkipMetrics:      cfg.Opts.Metrics.Skip,
 			SkipHealthChecks: cfg.Opts.HealthChecks.Skip,
 			HealthChecksDB:   cfg.Opts.HealthChecks.DB,
-			SwaggerTitle:     envs[swaggerUITitle].Value,
-			SwaggerPath:      envs[swaggerJSONPath].Value,
+			HealthChecksDBs:  cfg.Opts.HealthChecks.Databases.pingers,
+			MetricsGatherer:  prometheus.Gatherers{s.Gatherer, s.dbMetrics},
+			ShutdownState:    s.shutdownState,
+			SkipList:         router.NewSkipList(cfg.SkipRoutes...),
+			ConfigEnvs:       s.configEnvs(cfg),
+			SwaggerTitle:     s.envs[swaggerUITitle].Value,
+			SwaggerPath:      s.envs[swaggerJSONPath].Value,
 		},
 	)
 	if err != nil {
@@ -216,8 +381,8 @@ func (s *server) setup(cfg *Config) error {
 				return errors.New("error registering plugin validation: " + err.Error())
 			}
 		}
+		fastechoRouter.SkipList.Add(plugin.SkipRoutes...)
 		// Register plugin routes
-		fmt.Println("Registering plugin routes")
 		err = plugin.Routes(s.Echo, fastechoRouter)
 		if err != nil {
 			return errors.New("error registering plugin routes: " + err.Error())
@@ -226,6 +391,12 @@ func (s *server) setup(cfg *Config) error {
 	s.Echo.Validator = vdt
 	s.Router = fastechoRouter
 
+	// register lifecycle hooks, plugin hooks follow the ones of the service
+	s.addHooks(cfg.OnStart, cfg.OnStop)
+	for _, plugin := range cfg.Plugins {
+		s.addHooks(plugin.OnStart, plugin.OnStop)
+	}
+
 	return err
 }
 
@@ -233,21 +404,98 @@ func (s *server) config(cfg *Config) error {
 	// Set environment variables MUST be the first step
 	// merge default env vars with extra env vars
 
+	extraEnvs, err := extraEnvMap(cfg)
+	if err != nil {
+		return err
+	}
+
 	var allEnvs = make(env.Map)
-	maps.Copy(allEnvs, envs)
-	maps.Copy(allEnvs, cfg.ExtraEnvs)
+	maps.Copy(allEnvs, newEnvs())
+	maps.Copy(allEnvs, newTLSEnvs())
+	maps.Copy(allEnvs, extraEnvs)
+
+	sources, dotEnvSources, missingDotEnvFiles, err := cfg.Opts.Env.sources()
+	if err != nil {
+		return err
+	}
+
+	// the environment of the process is captured before the dotenv files are exported to it, so that
+	// changes to the files are picked up when the variables are reloaded
+	watchSources := sources
+	if len(dotEnvSources) > 0 {
+		environ, err := env.Environ().Load()
+		if err != nil {
+			return err
+		}
+		watchSources = append([]env.Source{env.Vars("env", environ)}, dotEnvSources...)
+	}
 
-	err := allEnvs.SetEnv()
+	err = allEnvs.SetEnvFrom(sources...)
 	if err != nil {
 		return err
 	}
 
+	// make the variables of the dotenv files visible to libraries reading the environment directly
+	err = env.Export(dotEnvSources...)
+	if err != nil {
+		return err
+	}
+
+	s.envs = allEnvs
+
+	// populate the typed configuration of the service
+	if cfg.ExtraConfig != nil {
+		err = allEnvs.Populate(cfg.ExtraConfig)
+		if err != nil {
+			return err
+		}
+	}
+
 	logger, err := echozap.New()
 	if err != nil {
 		return err
 	}
 	s.Logger = logger
 
+	if len(missingDotEnvFiles) > 0 {
+		s.Logger.Info("Configuration files don't exist. Don't worry, fastecho will read the environment variables.",
+			zap.Strings("files", missingDotEnvFiles),
+		)
+	}
+
+	if cfg.Opts.Env.Watch != nil {
+		err = s.watchEnvs(cfg.Opts.Env.Watch, watchSources)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.configShutdown(cfg.Opts.Shutdown)
+
+	s.tlsConfig, err = newTLSConfig(s.envs, s.Logger)
+	if err != nil {
+		return err
+	}
+
+	if !stringutils.IsEmpty(s.envs[adminPort].Value) {
+		s.adminAddr = net.JoinHostPort(s.envs[hostname].Value, s.envs[adminPort].Value)
+	} else if cfg.Opts.Env.ExposeConfig {
+		return errors.New("exposing the configuration requires the admin server, set `" + adminPort + "`")
+	}
+
+	s.Registerer, s.Gatherer = metricsRegistry(cfg.Opts.Metrics.Registry)
+	s.registered = &serverRegisterer{Registerer: s.Registerer}
+
+	// the metrics of the databases are collected by a registry of their own, as the registry of the server
+	// can't unregister collectors which don't describe their metrics upfront
+	if !cfg.Opts.Metrics.Skip {
+		s.dbMetrics = prometheus.NewRegistry()
+		err = s.dbMetrics.Register(newDBCollector(cfg.Opts.HealthChecks.DB, cfg.Opts.HealthChecks.Databases))
+		if err != nil {
+			return err
+		}
+	}
+
 	// only init tracing if it's not disabled
 	var tracerProvider *sdktrace.TracerProvider
 	var tracer *trace.Tracer
@@ -265,100 +513,460 @@ func (s *server) config(cfg *Config) error {
 
 		s.Tracer = tracer
 		s.TracerProvider = tracerProvider
+		s.Propagator = newPropagator()
+
+		if !cfg.Opts.Tracing.Isolated {
+			setGlobalTracer(s.TracerProvider, s.Propagator)
+		}
 	}
 
 	return nil
 }
 
+// sources returns the sources of the variables in order of precedence, along with the dotenv files among them
+// and the ones that don't exist. The dotenv files complement the environment of the process, unless the
+// sources are given explicitly.
+func (o EnvOpts) sources() ([]env.Source, []env.Source, []string, error) {
+	if len(o.Sources) > 0 {
+		return o.Sources, nil, nil, nil
+	}
+
+	dotEnvSources, missing, err := o.DotEnv.Sources()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return append([]env.Source{env.Environ()}, dotEnvSources...), dotEnvSources, missing, nil
+}
+
+// watchEnvs sets up the watcher reloading the variables at runtime, which runs while the server is started.
+func (s *server) watchEnvs(watchFn func(w *env.Watcher) error, sources []env.Source) error {
+	w := env.NewWatcher(s.envs, sources, env.WatchOpts{Logger: s.Logger})
+	s.envWatcher = w
+
+	err := watchFn(w)
+	if err != nil {
+		return err
+	}
+
+	var stop gocontext.CancelFunc
+	s.addHooks(
+		[]Hook{func(_ gocontext.Context) error {
+			var ctx gocontext.Context
+			ctx, stop = gocontext.WithCancel(gocontext.Background())
+			w.Start(ctx)
+			return nil
+		}},
+		[]Hook{func(_ gocontext.Context) error {
+			stop()
+			return nil
+		}},
+	)
+
+	return nil
+}
+
+// configEnvs returns the function returning the variables served by the config endpoint, or nil if the endpoint
+// is disabled. When the variables are watched, their current values are served.
+func (s *server) configEnvs(cfg *Config) func() env.Map {
+	if !cfg.Opts.Env.ExposeConfig {
+		return nil
+	}
+
+	if s.envWatcher != nil {
+		return s.envWatcher.Map
+	}
+
+	return func() env.Map {
+		return s.envs
+	}
+}
+
+// extraEnvMap returns the Map of the extra env vars, i.e. the ExtraEnvs and the variables of the ExtraConfig.
+func extraEnvMap(cfg *Config) (env.Map, error) {
+	if cfg.ExtraConfig == nil {
+		return cfg.ExtraEnvs, nil
+	}
+
+	extraConfig, err := env.MapOf(cfg.ExtraConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	extraEnvs := make(env.Map, len(cfg.ExtraEnvs)+len(extraConfig))
+	maps.Copy(extraEnvs, cfg.ExtraEnvs)
+	maps.Copy(extraEnvs, extraConfig)
+
+	return extraEnvs, nil
+}
+
+// configShutdown resolves the graceful shutdown options. Values given in the Config take precedence
+// over the environment variables.
+func (s *server) configShutdown(opts ShutdownOpts) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = s.envs[shutdownTimeout].DurationValue
+	}
+
+	if len(opts.Signals) == 0 {
+		opts.Signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+
+	s.shutdownOpts = opts
+}
+
 // middlewares configures all the middlewares for Echo.
-func (s *server) middlewares(cfg *Config) {
+// Recover is the outermost middleware by default, so that panics in any other middleware are recovered.
+func (s *server) middlewares(cfg *Config) error {
+	p := &Pipeline{}
+
+	// Recover
+	_ = p.Append(Middleware{Name: MiddlewareRecover, Func: middleware.Recover()})
+
 	if !cfg.Opts.Tracing.Skip {
-		s.Echo.Use(otel.Middleware(
-			otel.WithSkipper(func(ctx echo.Context) bool {
-				return isSwaggerRoute(ctx) || isMetricsRoute(ctx) || isHealthRoute(ctx)
-			}),
-			otel.WithServiceName(cfg.Opts.Tracing.ServiceName),
-			otel.WithEnv(envs[envType].Value),
-		))
+		_ = p.Append(Middleware{
+			Name: MiddlewareTracing,
+			Func: otel.Middleware(
+				otel.WithSkipper(func(ctx echo.Context) bool {
+					return s.Router.SkipList.Skip(ctx)
+				}),
+				otel.WithTracerProvider(s.TracerProvider),
+				otel.WithPropagators(s.Propagator),
+				otel.WithServiceName(cfg.Opts.Tracing.ServiceName),
+				otel.WithEnv(s.envs[envType].Value),
+			),
+		})
 	}
 
 	// Request ID
-	s.Echo.Use(middleware.RequestIDWithConfig(middleware.RequestIDConfig{
-		Skipper: func(ctx echo.Context) bool {
-			return isSwaggerRoute(ctx) || isMetricsRoute(ctx) || isHealthRoute(ctx)
-		},
-		Generator: func() string {
-			return uuid.New().String()
-		},
-	}))
+	_ = p.Append(Middleware{
+		Name: MiddlewareRequestID,
+		Func: middleware.RequestIDWithConfig(middleware.RequestIDConfig{
+			Skipper: func(ctx echo.Context) bool {
+				return s.Router.SkipList.Skip(ctx)
+			},
+			Generator: func() string {
+				return uuid.New().String()
+			},
+		}),
+	})
 
 	// Zap Logger
-	s.Echo.Use(echozap.ZapLoggerMiddlewareWithConfig(s.Logger, echozap.ZapLoggerMiddlewareConfig{
-		Skipper: func(ctx echo.Context) bool {
-			return isSwaggerRoute(ctx) || isMetricsRoute(ctx) || isHealthRoute(ctx)
-		},
-	}))
+	_ = p.Append(Middleware{
+		Name: MiddlewareLogger,
+		Func: echozap.ZapLoggerMiddlewareWithConfig(s.Logger, echozap.ZapLoggerMiddlewareConfig{
+			Skipper: func(ctx echo.Context) bool {
+				return s.Router.SkipList.Skip(ctx)
+			},
+		}),
+	})
 
 	// Context
-	s.Echo.Use(context.ServiceContextMiddleware(s.Logger, s.Tracer, cfg.ContextProps))
+	_ = p.Append(Middleware{
+		Name: MiddlewareContext,
+		Func: context.ServiceContextMiddleware(s.Logger, s.Tracer, cfg.ContextProps),
+	})
 
 	// Gzip
-	s.Echo.Use(middleware.GzipWithConfig(middleware.GzipConfig{
-		Skipper: func(ctx echo.Context) bool {
-			return isSwaggerRoute(ctx) || isMetricsRoute(ctx)
-		},
-	}))
+	_ = p.Append(Middleware{
+		Name: MiddlewareGzip,
+		Func: middleware.GzipWithConfig(middleware.GzipConfig{
+			Skipper: func(ctx echo.Context) bool {
+				return s.Router.SkipList.Skip(ctx)
+			},
+		}),
+	})
 
 	// Metrics
 	if !cfg.Opts.Metrics.Skip {
-		s.Echo.Use(echoprometheus.NewMiddleware("echo_http"))
+		metrics, err := echoprometheus.MiddlewareConfig{
+			Skipper: func(ctx echo.Context) bool {
+				return s.Router.SkipList.Skip(ctx)
+			},
+			Subsystem:  "echo_http",
+			Registerer: s.registered,
+		}.ToMiddleware()
+		if err != nil {
+			return err
+		}
+
+		_ = p.Append(Middleware{
+			Name: MiddlewareMetrics,
+			Func: metrics,
+		})
 	}
 
-	// Recover
-	s.Echo.Use(middleware.Recover())
+	// Transaction
+	if cfg.Opts.Transactions.DB != nil {
+		_ = p.Append(Middleware{
+			Name: MiddlewareTx,
+			Func: s.txMiddleware(cfg.Opts.Transactions),
+		})
+	}
+
+	// Allow customizing the pipeline
+	if cfg.PipelineFn != nil {
+		err := cfg.PipelineFn(p)
+		if err != nil {
+			return err
+		}
+	}
+
+	p.use(s.Echo)
+
+	return nil
 }
 
-// run starts the server and listens for interrupt signals to gracefully shut it down.
+// prepare applies the custom Echo configuration and registers the routes to Echo.
+func (s *server) prepare(cfg *Config) error {
+	// Allow custom Echo configuration
+	if cfg.EchoFn != nil {
+		err := cfg.EchoFn(s.Echo)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := s.Router.Setup()
+	if err != nil {
+		return err
+	}
+
+	s.Router.PrintRoutes(s.Echo)
+
+	return nil
+}
+
+// run starts the server and listens for termination signals to gracefully shut it down.
 func (s *server) run(host string, port string) error {
-	// Defer the shutdown of the tracer provider
-	defer func() {
-		if s.TracerProvider != nil {
-			_ = s.TracerProvider.Shutdown(gocontext.Background())
+	ln, err := s.listen(host, port)
+	if err != nil {
+		return err
+	}
+
+	return s.runOn(ln)
+}
+
+// runOn serves requests on the given listener until a termination signal is received or a stop is
+// requested, and then gracefully shuts the server down.
+func (s *server) runOn(ln net.Listener) error {
+	serverErr, err := s.startServing(gocontext.Background(), ln)
+	if err != nil {
+		return err
+	}
+
+	// Wait for a termination signal or a programmatic stop to gracefully shut down the server.
+	// Use a buffered channel to avoid missing signals as recommended for signal.Notify
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, s.shutdownOpts.Signals...)
+	defer signal.Stop(quit)
+
+	select {
+	case err = <-serverErr:
+		s.stopUnexpectedly(err)
+		return err
+	case sig := <-quit:
+		s.Logger.Info("Shutting down the server", zap.String("signal", sig.String()))
+	case <-s.shutdownOpts.Stop:
+		s.Logger.Info("Shutting down the server", zap.String("reason", "stop requested"))
+	}
+
+	// The drain phase doesn't eat into the time given to in-flight requests
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), s.drainDelay+s.shutdownOpts.Timeout)
+	defer cancel()
+
+	return s.shutdown(ctx)
+}
+
+// listen binds a TCP listener to the given host and port.
+func (s *server) listen(host string, port string) (net.Listener, error) {
+	serviceURL := net.JoinHostPort(host, port)
+
+	return net.Listen("tcp", serviceURL)
+}
+
+// startServing invokes the OnStart hooks and serves requests on the given listener in the background.
+// The listener is closed if a hook fails. The returned channel receives the result of serving,
+// which is nil when the server is shut down gracefully.
+func (s *server) startServing(ctx gocontext.Context, ln net.Listener) (<-chan error, error) {
+	var adminLn net.Listener
+	if s.AdminEcho != nil {
+		var err error
+		adminLn, err = net.Listen("tcp", s.adminAddr)
+		if err != nil {
+			_ = ln.Close()
+			return nil, err
 		}
-	}()
+	}
+
+	err := s.start(ctx)
+	if err != nil {
+		_ = ln.Close()
+		if adminLn != nil {
+			_ = adminLn.Close()
+		}
+		return nil, err
+	}
 
-	// Start server
+	if s.tlsConfig != nil {
+		ln = tls.NewListener(ln, s.tlsConfig)
+	}
+
+	serverErr := make(chan error, 2)
+	serve(s.Echo, ln, serverErr)
+	if adminLn != nil {
+		serve(s.AdminEcho, adminLn, serverErr)
+	}
+
+	return serverErr, nil
+}
+
+// serve serves requests of the given Echo on the listener in the background. The result of serving
+// is sent to the given channel, which is nil when the server is shut down gracefully.
+func serve(e *echo.Echo, ln net.Listener, serverErr chan<- error) {
+	e.Listener = ln
 	go func() {
-		serviceURL := fmt.Sprintf("%s:%v", host, port)
-		if err := s.Echo.Start(serviceURL); err != nil && err != http.ErrServerClosed {
-			s.Echo.Logger.Panicf("Shutting down the server! \n%s", err)
+		err := e.Start(ln.Addr().String())
+		if errors.Is(err, http.ErrServerClosed) {
+			err = nil
 		}
+		serverErr <- err
 	}()
+}
 
-	// Wait for interrupt signal to gracefully shut down the server with a timeout of 10 seconds.
-	// Use a buffered channel to avoid missing signals as recommended for signal.Notify
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt)
-	<-quit
+// shutdown gracefully shuts down Echo and flushes the tracer provider.
+// The readiness check starts failing immediately, while live traffic is still served during the drain delay.
+// Subsequent calls return the result of the first one.
+func (s *server) shutdown(ctx gocontext.Context) error {
+	s.shutdownOnce.Do(func() {
+		s.shutdownState.Begin()
+		s.drain(ctx)
 
-	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), 10*time.Second)
-	defer cancel()
+		s.shutdownErr = s.close(ctx)
+	})
+
+	return s.shutdownErr
+}
 
+// close shuts down Echo and the admin server, then invokes the OnStop hooks and flushes the tracer provider.
+func (s *server) close(ctx gocontext.Context) error {
 	err := s.Echo.Shutdown(ctx)
+	// The admin server is shut down last, so that health checks are served until the end
+	if s.AdminEcho != nil {
+		err = errors.Join(err, s.AdminEcho.Shutdown(ctx))
+	}
+	err = errors.Join(err, s.stop(ctx))
+	_ = s.shutdownTracer(ctx)
+	s.unregisterMetrics()
+
 	return err
 }
 
-// isMetricsRoute returns whether the request is to metrics endpoint.
-func isMetricsRoute(ctx echo.Context) bool {
-	return strings.Contains(ctx.Request().URL.Path, "/metrics")
+// unregisterMetrics unregisters the collectors of fastecho from the registry of the server, so that another server
+// can register them again.
+func (s *server) unregisterMetrics() {
+	if s.registered != nil {
+		s.registered.unregisterAll()
+	}
 }
 
-// isSwaggerRoute returns whether the request is to swagger endpoint.
-func isSwaggerRoute(ctx echo.Context) bool {
-	return strings.Contains(ctx.Request().URL.Path, "/swagger/")
+// addHooks registers the OnStart and OnStop hooks of the service, a plugin or fastecho itself. The OnStop
+// hook at a given position undoes the OnStart hook at the same position.
+func (s *server) addHooks(onStart, onStop []Hook) {
+	for i := range max(len(onStart), len(onStop)) {
+		var hook lifecycleHook
+		if i < len(onStart) {
+			hook.start = onStart[i]
+		}
+		if i < len(onStop) {
+			hook.stop = onStop[i]
+		}
+		s.hooks = append(s.hooks, hook)
+	}
+}
+
+// start invokes the OnStart hooks in order. The first failing hook aborts the startup, in which case the
+// hooks started before it are stopped.
+func (s *server) start(ctx gocontext.Context) error {
+	s.hooksMu.Lock()
+	defer s.hooksMu.Unlock()
+
+	for i, hook := range s.hooks {
+		if hook.start != nil {
+			if err := hook.start(ctx); err != nil {
+				s.Logger.Error("OnStart hook failed, aborting startup", zap.Int("hook", i), zap.Error(err))
+				_ = s.stopStarted(ctx)
+				return err
+			}
+		}
+		s.started = i + 1
+	}
+
+	return nil
 }
 
-// isHealthRoute returns whether the request is to health endpoint.
-func isHealthRoute(ctx echo.Context) bool {
-	return strings.Contains(ctx.Request().URL.Path, "/health")
+// stop invokes the OnStop hooks of the started hooks in reverse order. All of them are invoked, even if
+// some of them fail, and none of them is invoked twice.
+func (s *server) stop(ctx gocontext.Context) error {
+	s.hooksMu.Lock()
+	defer s.hooksMu.Unlock()
+
+	return s.stopStarted(ctx)
+}
+
+// stopStarted invokes the OnStop hooks of the started hooks in reverse order. The caller holds hooksMu.
+func (s *server) stopStarted(ctx gocontext.Context) error {
+	var err error
+	for i := s.started - 1; i >= 0; i-- {
+		if s.hooks[i].stop == nil {
+			continue
+		}
+		if hookErr := s.hooks[i].stop(ctx); hookErr != nil {
+			s.Logger.Error("OnStop hook failed", zap.Int("hook", i), zap.Error(hookErr))
+			err = errors.Join(err, hookErr)
+		}
+	}
+	s.started = 0
+
+	return err
+}
+
+// stopUnexpectedly shuts down the servers still serving, invokes the OnStop hooks and flushes the tracer
+// provider once serving failed. There is no drain, as the service is already failing.
+func (s *server) stopUnexpectedly(err error) {
+	s.Logger.Error("Server stopped unexpectedly", zap.Error(err))
+
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), s.shutdownOpts.Timeout)
+	defer cancel()
+
+	s.shutdownOnce.Do(func() {
+		s.shutdownState.Begin()
+
+		s.shutdownErr = s.close(ctx)
+	})
+}
+
+// drain waits for the drain delay to pass, or until the context is done.
+func (s *server) drain(ctx gocontext.Context) {
+	if s.drainDelay <= 0 {
+		return
+	}
+
+	s.Logger.Info("Draining the server before shutting down", zap.Duration("delay", s.drainDelay))
+
+	timer := time.NewTimer(s.drainDelay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// shutdownTracer flushes and stops the tracer provider, if tracing is enabled.
+func (s *server) shutdownTracer(ctx gocontext.Context) error {
+	if s.TracerProvider == nil {
+		return nil
+	}
+
+	return s.TracerProvider.Shutdown(ctx)
 }
@@ -0,0 +1,787 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fastecho
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+
+	"github.com/ingka-group/fastecho/env"
+	"github.com/ingka-group/fastecho/router"
+)
+
+func TestFastEcho_MultipleServers(t *testing.T) {
+	t.Setenv(port, "0")
+
+	newFastEcho := func(name string) *FastEcho {
+		fe, err := Initialize(&Config{
+			Routes: func(e *echo.Echo, _ *router.Router) error {
+				e.GET("/hello", func(c echo.Context) error {
+					return c.String(http.StatusOK, name)
+				})
+				return nil
+			},
+			Opts: Opts{
+				Metrics: MetricsOpts{Registry: prometheus.NewRegistry()},
+				Tracing: TracingOpts{Skip: true},
+			},
+		})
+		require.NoError(t, err)
+
+		return fe
+	}
+
+	servers := []*FastEcho{newFastEcho("a"), newFastEcho("b")}
+	assert.NotSame(t, servers[0].Gatherer(), servers[1].Gatherer())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for i, fe := range servers {
+		require.NoError(t, fe.Start(ctx))
+
+		body := get(t, "http://"+fe.Addr().String()+"/hello")
+		assert.Equal(t, []string{"a", "b"}[i], body)
+
+		metrics := get(t, "http://"+fe.Addr().String()+"/metrics")
+		assert.Contains(t, metrics, "echo_http_requests_total")
+	}
+
+	for _, fe := range servers {
+		require.NoError(t, fe.Shutdown(context.Background()))
+	}
+}
+
+func TestInitialize_globals(t *testing.T) {
+	t.Setenv(port, "0")
+
+	tracerProvider := otel.GetTracerProvider()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(tracerProvider)
+	})
+
+	tests := []struct {
+		name           string
+		opts           Opts
+		expectRegistry bool
+		expectGlobal   bool
+	}{
+		{
+			name:         "ok: defaults of Prometheus and OpenTelemetry",
+			opts:         Opts{Tracing: TracingOpts{ServiceName: "orders"}},
+			expectGlobal: true,
+		},
+		{
+			name: "ok: isolated",
+			opts: Opts{
+				Metrics: MetricsOpts{Registry: prometheus.NewRegistry()},
+				Tracing: TracingOpts{ServiceName: "orders", Isolated: true},
+			},
+			expectRegistry: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			otel.SetTracerProvider(tracerProvider)
+
+			fe, err := Initialize(&Config{Opts: tt.opts})
+			require.NoError(t, err)
+			defer fe.Shutdown(context.Background())
+
+			if tt.expectRegistry {
+				assert.Same(t, tt.opts.Metrics.Registry, fe.Registerer())
+				assert.Same(t, tt.opts.Metrics.Registry, fe.Gatherer())
+			} else {
+				assert.Equal(t, prometheus.DefaultRegisterer, fe.Registerer())
+				assert.Equal(t, prometheus.DefaultGatherer, fe.Gatherer())
+			}
+
+			if tt.expectGlobal {
+				assert.Same(t, fe.server.TracerProvider, otel.GetTracerProvider())
+			} else {
+				assert.NotSame(t, fe.server.TracerProvider, otel.GetTracerProvider())
+			}
+		})
+	}
+}
+
+func TestFastEcho_Shutdown_defaultRegistry(t *testing.T) {
+	t.Setenv(port, "0")
+
+	// the collectors are unregistered on shutdown, so that a server can be initialized again on the default registry
+	for range 2 {
+		fe, err := Initialize(&Config{Opts: Opts{Tracing: TracingOpts{Skip: true}}})
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		fe.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		assert.Contains(t, rec.Body.String(), "go_goroutines")
+
+		require.NoError(t, fe.Shutdown(context.Background()))
+	}
+}
+
+func TestFastEcho_Start(t *testing.T) {
+	stopped := make(chan struct{})
+
+	fe := newTestFastEcho(t, &Config{
+		Routes: helloRoutes,
+		OnStop: []Hook{func(context.Context) error {
+			close(stopped)
+			return nil
+		}},
+	})
+	assert.Nil(t, fe.Addr())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, fe.Start(ctx))
+
+	// the listener is bound once Start returns
+	require.NotNil(t, fe.Addr())
+	assert.NotEqual(t, 0, fe.Addr().(*net.TCPAddr).Port)
+	assert.Equal(t, "hello", get(t, "http://"+fe.Addr().String()+"/hello"))
+
+	// the server is shut down once the context is cancelled
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop once the context was cancelled")
+	}
+
+	_, err := http.Get("http://" + fe.Addr().String() + "/hello")
+	assert.Error(t, err)
+}
+
+func TestFastEcho_Serve(t *testing.T) {
+	fe := newTestFastEcho(t, &Config{Routes: helloRoutes})
+
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- fe.Serve(ln)
+	}()
+
+	assert.Eventually(t, func() bool {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/hello")
+		if err != nil {
+			return false
+		}
+		_ = resp.Body.Close()
+
+		return resp.StatusCode == http.StatusOK
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, fe.Shutdown(context.Background()))
+	assert.NoError(t, <-serveErr)
+}
+
+func TestInitialize_routerSetup(t *testing.T) {
+	fe := newTestFastEcho(t, &Config{
+		Routes: func(e *echo.Echo, r *router.Router) error {
+			// routes added to the router are only registered to Echo by Router.Setup
+			router.AddRoute(r, e.Group("/v1"), "/hello", func(c echo.Context) error {
+				return c.String(http.StatusOK, "hello")
+			}, http.MethodGet)
+			return nil
+		},
+	})
+
+	rec := httptest.NewRecorder()
+	fe.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/hello", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "hello", rec.Body.String())
+}
+
+func TestInitialize_adminIsolation(t *testing.T) {
+	t.Setenv(adminPort, "0")
+
+	fe := newTestFastEcho(t, &Config{Routes: helloRoutes})
+	require.NotNil(t, fe.AdminHandler())
+
+	tests := []struct {
+		name         string
+		path         string
+		expectPublic int
+		expectAdmin  int
+	}{
+		{
+			name:         "ok: metrics",
+			path:         "/metrics",
+			expectPublic: http.StatusNotFound,
+			expectAdmin:  http.StatusOK,
+		},
+		{
+			name:         "ok: readiness",
+			path:         "/health/ready",
+			expectPublic: http.StatusNotFound,
+			expectAdmin:  http.StatusOK,
+		},
+		{
+			name:         "ok: liveness",
+			path:         "/health/live",
+			expectPublic: http.StatusNotFound,
+			expectAdmin:  http.StatusOK,
+		},
+		{
+			name:         "ok: swagger",
+			path:         "/swagger",
+			expectPublic: http.StatusNotFound,
+			expectAdmin:  http.StatusOK,
+		},
+		{
+			name:         "ok: service routes",
+			path:         "/hello",
+			expectPublic: http.StatusOK,
+			expectAdmin:  http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			fe.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			assert.Equal(t, tt.expectPublic, rec.Code)
+
+			rec = httptest.NewRecorder()
+			fe.AdminHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			assert.Equal(t, tt.expectAdmin, rec.Code)
+		})
+	}
+}
+
+func TestInitialize_metricsSkipList(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	fe := newTestFastEcho(t, &Config{
+		Routes: helloRoutes,
+		Opts:   Opts{Metrics: MetricsOpts{Registry: registry}},
+	})
+
+	for _, path := range []string{"/hello", "/health/ready", "/metrics"} {
+		rec := httptest.NewRecorder()
+		fe.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	// the operational endpoints in the skip list aren't counted
+	var urls []string
+	for _, family := range families {
+		if family.GetName() != "echo_http_requests_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "url" {
+					urls = append(urls, label.GetValue())
+				}
+			}
+		}
+	}
+	assert.Equal(t, []string{"/hello"}, urls)
+}
+
+func TestInitialize_extraConfig(t *testing.T) {
+	t.Setenv("GREETING", "hej")
+
+	var extraConfig struct {
+		Greeting string `env:"GREETING"`
+	}
+
+	fe := newTestFastEcho(t, &Config{
+		ExtraEnvs:   env.Map{"MODE": {DefaultValue: "strict"}},
+		ExtraConfig: &extraConfig,
+	})
+
+	assert.Equal(t, "hej", extraConfig.Greeting)
+	assert.Equal(t, "hej", fe.server.envs["GREETING"].Value)
+	assert.Equal(t, "strict", fe.server.envs["MODE"].Value)
+}
+
+func TestInitialize_exposeConfig(t *testing.T) {
+	t.Setenv("API_TOKEN", "s3cr3t")
+
+	newConfig := func() *Config {
+		return &Config{
+			ExtraEnvs: env.Map{"API_TOKEN": {Secret: true}},
+			Opts: Opts{
+				Env:     EnvOpts{ExposeConfig: true},
+				Tracing: TracingOpts{Skip: true},
+			},
+		}
+	}
+
+	t.Run("ok: hosted by the admin server", func(t *testing.T) {
+		t.Setenv(adminPort, "0")
+		fe := newTestFastEcho(t, newConfig())
+
+		rec := httptest.NewRecorder()
+		fe.AdminHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"API_TOKEN":{"value":"******"`)
+		assert.NotContains(t, rec.Body.String(), "s3cr3t")
+
+		rec = httptest.NewRecorder()
+		fe.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config", nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("ok: current values when watched", func(t *testing.T) {
+		t.Setenv(adminPort, "0")
+
+		dotEnv := filepath.Join(t.TempDir(), ".env")
+		require.NoError(t, os.WriteFile(dotEnv, []byte("MODE=strict\n"), 0o600))
+
+		var watcher *env.Watcher
+		cfg := newConfig()
+		cfg.ExtraEnvs["MODE"] = &env.Var{}
+		cfg.Opts.Env.Sources = []env.Source{env.Environ(), env.DotEnv(dotEnv)}
+		cfg.Opts.Env.Watch = func(w *env.Watcher) error {
+			watcher = w
+			return nil
+		}
+		fe := newTestFastEcho(t, cfg)
+
+		require.NoError(t, os.WriteFile(dotEnv, []byte("MODE=lenient\n"), 0o600))
+		require.NoError(t, watcher.Reload())
+
+		rec := httptest.NewRecorder()
+		fe.AdminHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config", nil))
+		assert.Contains(t, rec.Body.String(), `"MODE":{"value":"lenient"`)
+	})
+
+	t.Run("error: without the admin server", func(t *testing.T) {
+		t.Setenv(port, "0")
+		t.Setenv(adminPort, "")
+
+		_, err := Initialize(newConfig())
+		assert.ErrorContains(t, err, adminPort)
+	})
+}
+
+func TestInitialize_databases(t *testing.T) {
+	t.Setenv(adminPort, "0")
+	t.Setenv("LATE_"+dbDriverName, DriverSQLite)
+	t.Setenv("LATE_"+dbName, sqliteMemory)
+
+	dbs := NewDatabases()
+	fe := newTestFastEcho(t, &Config{
+		Opts: Opts{HealthChecks: HealthChecksOpts{Databases: dbs}},
+	})
+	// another server doesn't know about the databases of the first one
+	other := newTestFastEcho(t, &Config{})
+
+	serve := func(fe *FastEcho, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		fe.AdminHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	// databases created once the server is set up are checked and export their metrics
+	db, err := dbs.NewDB("late", nil, WithoutMigrations(), WithLogger(zap.NewNop()))
+	require.NoError(t, err)
+
+	assert.Contains(t, serve(fe, "/metrics").Body.String(), `go_sql_open_connections{db_name="late`)
+	assert.NotContains(t, serve(other, "/metrics").Body.String(), `db_name="late`)
+	assert.Equal(t, http.StatusOK, serve(fe, "/health/ready").Code)
+
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	require.NoError(t, sqlDB.Close())
+	assert.Equal(t, http.StatusServiceUnavailable, serve(fe, "/health/ready").Code)
+	assert.Equal(t, http.StatusOK, serve(other, "/health/ready").Code)
+}
+
+func TestServer_configShutdown(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          ShutdownOpts
+		expectTimeout time.Duration
+	}{
+		{
+			name:          "ok: SHUTDOWN_TIMEOUT by default",
+			expectTimeout: 5 * time.Second,
+		},
+		{
+			name:          "ok: Opts.Shutdown.Timeout takes precedence",
+			opts:          ShutdownOpts{Timeout: 2 * time.Second},
+			expectTimeout: 2 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &server{envs: env.Map{shutdownTimeout: {DurationValue: 5 * time.Second}}}
+			s.configShutdown(tt.opts)
+
+			assert.Equal(t, tt.expectTimeout, s.shutdownOpts.Timeout)
+			assert.Equal(t, []os.Signal{os.Interrupt, syscall.SIGTERM}, s.shutdownOpts.Signals)
+		})
+	}
+}
+
+func TestServer_runOn_stop(t *testing.T) {
+	stop := make(chan struct{})
+	entered := make(chan struct{})
+
+	fe := newTestFastEcho(t, &Config{
+		Routes: func(e *echo.Echo, _ *router.Router) error {
+			e.GET("/slow", func(c echo.Context) error {
+				close(entered)
+				time.Sleep(300 * time.Millisecond)
+				return c.String(http.StatusOK, "done")
+			})
+			return nil
+		},
+		Opts: Opts{
+			Shutdown: ShutdownOpts{Timeout: 5 * time.Second, Stop: stop},
+		},
+	})
+
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- fe.server.runOn(ln)
+	}()
+
+	body := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/slow")
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+
+		b, _ := io.ReadAll(resp.Body)
+		body <- string(b)
+	}()
+
+	// the request is in flight when the stop is requested
+	<-entered
+	close(stop)
+
+	select {
+	case err := <-runErr:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not shut down within the timeout")
+	}
+	assert.Equal(t, "done", <-body)
+}
+
+func TestServer_runOn_signal(t *testing.T) {
+	// keep the test process alive if SIGTERM arrives before the server listens for it
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	fe := newTestFastEcho(t, &Config{})
+
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- fe.server.runOn(ln)
+	}()
+
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(5 * time.Second)
+
+	for {
+		select {
+		case err := <-runErr:
+			require.NoError(t, err)
+			return
+		case <-ticker.C:
+			require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGTERM))
+		case <-timeout:
+			t.Fatal("server did not shut down on SIGTERM")
+		}
+	}
+}
+
+func TestFastEcho_Shutdown_once(t *testing.T) {
+	var stops atomic.Int32
+
+	fe := newTestFastEcho(t, &Config{
+		OnStop: []Hook{func(context.Context) error {
+			stops.Add(1)
+			return nil
+		}},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, fe.Start(ctx))
+
+	require.NoError(t, fe.Shutdown(context.Background()))
+	require.NoError(t, fe.Shutdown(context.Background()))
+	assert.Equal(t, int32(1), stops.Load())
+}
+
+func TestFastEcho_Shutdown_drain(t *testing.T) {
+	fe := newTestFastEcho(t, &Config{
+		Routes: helloRoutes,
+		Opts: Opts{
+			HealthChecks: HealthChecksOpts{DrainDelay: time.Second},
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, fe.Start(ctx))
+	addr := "http://" + fe.Addr().String()
+
+	assert.Equal(t, http.StatusOK, status(t, addr+"/health/ready"))
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		shutdownErr <- fe.Shutdown(context.Background())
+	}()
+
+	// readiness fails as soon as the shutdown begins, while live traffic is still served
+	assert.Eventually(t, func() bool {
+		resp, err := http.Get(addr + "/health/ready")
+		if err != nil {
+			return false
+		}
+		_ = resp.Body.Close()
+
+		return resp.StatusCode == http.StatusServiceUnavailable
+	}, 500*time.Millisecond, 10*time.Millisecond)
+	assert.Equal(t, http.StatusOK, status(t, addr+"/hello"))
+
+	require.NoError(t, <-shutdownErr)
+}
+
+func TestServer_hooks(t *testing.T) {
+	errHook := errors.New("hook failed")
+
+	tests := []struct {
+		name         string
+		given        func(record func(event string, err error) Hook) *Config
+		expectErr    error
+		expectEvents []string
+	}{
+		{
+			name: "ok: plugin hooks follow the ones of the config on start and precede them on stop",
+			given: func(record func(string, error) Hook) *Config {
+				return &Config{
+					OnStart: []Hook{record("start config 1", nil), record("start config 2", nil)},
+					OnStop:  []Hook{record("stop config 1", nil), record("stop config 2", nil)},
+					Plugins: []Plugin{{
+						Routes:  noRoutes,
+						OnStart: []Hook{record("start plugin", nil)},
+						OnStop:  []Hook{record("stop plugin", nil)},
+					}},
+				}
+			},
+			expectEvents: []string{
+				"start config 1", "start config 2", "start plugin",
+				"stop plugin", "stop config 2", "stop config 1",
+			},
+		},
+		{
+			name: "ok: stop hooks without start hook",
+			given: func(record func(string, error) Hook) *Config {
+				return &Config{
+					OnStart: []Hook{record("start config", nil)},
+					OnStop:  []Hook{record("stop config", nil), record("flush config", nil)},
+				}
+			},
+			expectEvents: []string{"start config", "flush config", "stop config"},
+		},
+		{
+			name: "error: failing hook stops the hooks started before it",
+			given: func(record func(string, error) Hook) *Config {
+				return &Config{
+					OnStart: []Hook{record("start config 1", nil), record("start config 2", errHook)},
+					OnStop:  []Hook{record("stop config 1", nil), record("stop config 2", nil)},
+					Plugins: []Plugin{{
+						Routes:  noRoutes,
+						OnStart: []Hook{record("start plugin", nil)},
+						OnStop:  []Hook{record("stop plugin", nil)},
+					}},
+				}
+			},
+			expectErr:    errHook,
+			expectEvents: []string{"start config 1", "start config 2", "stop config 1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []string
+			record := func(event string, err error) Hook {
+				return func(context.Context) error {
+					events = append(events, event)
+					return err
+				}
+			}
+
+			fe := newTestFastEcho(t, tt.given(record))
+
+			err := fe.server.start(context.Background())
+			require.ErrorIs(t, err, tt.expectErr)
+			if err == nil {
+				require.NoError(t, fe.server.stop(context.Background()))
+			}
+
+			assert.Equal(t, tt.expectEvents, events)
+		})
+	}
+}
+
+func TestFastEcho_Shutdown_notStarted(t *testing.T) {
+	var stops atomic.Int32
+
+	fe := newTestFastEcho(t, &Config{
+		OnStop: []Hook{func(context.Context) error {
+			stops.Add(1)
+			return nil
+		}},
+	})
+
+	require.NoError(t, fe.Shutdown(context.Background()))
+	assert.Zero(t, stops.Load())
+}
+
+func TestFastEcho_Serve_stoppedUnexpectedly(t *testing.T) {
+	t.Setenv(adminPort, "0")
+
+	started := make(chan struct{})
+	var stops atomic.Int32
+	var adminServing atomic.Bool
+
+	var fe *FastEcho
+	fe = newTestFastEcho(t, &Config{
+		OnStart: []Hook{func(context.Context) error {
+			close(started)
+			return nil
+		}},
+		OnStop: []Hook{func(context.Context) error {
+			stops.Add(1)
+			// the admin server is shut down before the hooks release the resources it may use
+			conn, err := net.Dial("tcp", fe.AdminAddr().String())
+			if err == nil {
+				adminServing.Store(true)
+				_ = conn.Close()
+			}
+			return nil
+		}},
+	})
+
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- fe.Serve(ln)
+	}()
+
+	// serving fails once the listener is closed underneath the server
+	<-started
+	require.NoError(t, ln.Close())
+
+	select {
+	case err := <-serveErr:
+		require.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop")
+	}
+	assert.Equal(t, int32(1), stops.Load())
+	assert.False(t, adminServing.Load())
+
+	// the shutdown already happened
+	require.NoError(t, fe.Shutdown(context.Background()))
+	assert.Equal(t, int32(1), stops.Load())
+}
+
+func helloRoutes(e *echo.Echo, _ *router.Router) error {
+	e.GET("/hello", func(c echo.Context) error {
+		return c.String(http.StatusOK, "hello")
+	})
+	return nil
+}
+
+func noRoutes(*echo.Echo, *router.Router) error {
+	return nil
+}
+
+// newTestFastEcho initializes a FastEcho bound to a random port, without tracing.
+func newTestFastEcho(t *testing.T, cfg *Config) *FastEcho {
+	t.Helper()
+	t.Setenv(port, "0")
+
+	cfg.Opts.Tracing.Skip = true
+	if cfg.Opts.Metrics.Registry == nil {
+		cfg.Opts.Metrics.Registry = prometheus.NewRegistry()
+	}
+	fe, err := Initialize(cfg)
+	require.NoError(t, err)
+
+	return fe
+}
+
+func status(t *testing.T, url string) int {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	return resp.StatusCode
+}
+
+func get(t *testing.T, url string) string {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return string(body)
+}
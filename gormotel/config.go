@@ -0,0 +1,79 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gormotel
+
+import (
+	"database/sql"
+
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+type Option interface {
+	apply(*Config)
+}
+
+type optionFunc func(*Config)
+
+func (o optionFunc) apply(c *Config) {
+	o(c)
+}
+
+// Config contains the configuration of the Plugin.
+type Config struct {
+	DBName         string
+	TracerProvider oteltrace.TracerProvider
+	Replicas       map[string]*sql.DB
+	SkipTracing    bool
+	SkipMetrics    bool
+}
+
+// WithDBName specifies the name of the database, which labels its metrics and spans. Defaults to `default`.
+func WithDBName(name string) Option {
+	return optionFunc(func(cfg *Config) {
+		if name != "" {
+			cfg.DBName = name
+		}
+	})
+}
+
+// WithTracerProvider specifies a tracer provider to use for queries without a span in their context.
+// By default, a span is only created under the span of the request, with the provider of that span.
+func WithTracerProvider(provider oteltrace.TracerProvider) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.TracerProvider = provider
+	})
+}
+
+// WithReplicas specifies the connections of the replicas of the database, the pool statistics of which are
+// exported along with the ones of the primary.
+func WithReplicas(replicas map[string]*sql.DB) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.Replicas = replicas
+	})
+}
+
+// WithoutTracing disables the spans of the queries.
+func WithoutTracing() Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.SkipTracing = true
+	})
+}
+
+// WithoutMetrics disables the metrics of the queries and the connection pools.
+func WithoutMetrics() Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.SkipMetrics = true
+	})
+}
@@ -0,0 +1,212 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gormotel provides a GORM plugin which traces the queries with OpenTelemetry and exports their
+// metrics, and the ones of the connection pools, to Prometheus.
+package gormotel
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const (
+	// PluginName is the name under which the plugin is registered to GORM.
+	PluginName = "fastecho:otel"
+	// ScopeName is the instrumentation scope name.
+	ScopeName = "github.com/ingka-group/fastecho/gormotel"
+
+	defaultDBName = "default"
+
+	spanKey  = "fastecho:otel_span"
+	startKey = "fastecho:otel_start"
+)
+
+// Plugin is a GORM plugin which creates a span for each query under the span of its context, and exports
+// the latency of the queries and the statistics of the connection pools. It is a prometheus.Collector, so it
+// is registered to a registry to export its metrics.
+type Plugin struct {
+	config     Config
+	system     attribute.KeyValue
+	durations  *prometheus.HistogramVec
+	collectors []prometheus.Collector
+}
+
+// New creates a new Plugin.
+func New(options ...Option) *Plugin {
+	config := Config{DBName: defaultDBName}
+	for _, opt := range options {
+		opt.apply(&config)
+	}
+
+	return &Plugin{
+		config: config,
+		durations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   "gorm",
+			Name:        "query_duration_seconds",
+			Help:        "Duration of the queries of the database.",
+			Buckets:     prometheus.DefBuckets,
+			ConstLabels: prometheus.Labels{"db": config.DBName},
+		}, []string{"operation", "table", "status"}),
+	}
+}
+
+// Name returns the name of the plugin.
+func (p *Plugin) Name() string {
+	return PluginName
+}
+
+// Initialize registers the callbacks of the plugin to the database.
+func (p *Plugin) Initialize(db *gorm.DB) error {
+	p.system = dbSystem(db.Dialector.Name())
+
+	if !p.config.SkipMetrics {
+		sqlDb, err := db.DB()
+		if err != nil {
+			return err
+		}
+
+		p.collectors = append(p.collectors, collectors.NewDBStatsCollector(sqlDb, p.config.DBName))
+		for name, replica := range p.config.Replicas {
+			p.collectors = append(p.collectors, collectors.NewDBStatsCollector(replica, p.config.DBName+"/"+name))
+		}
+	}
+
+	cb := db.Callback()
+
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register(PluginName+":before_create", p.before("create")),
+		cb.Create().After("gorm:create").Register(PluginName+":after_create", p.after("create")),
+		cb.Query().Before("gorm:query").Register(PluginName+":before_query", p.before("query")),
+		cb.Query().After("gorm:query").Register(PluginName+":after_query", p.after("query")),
+		cb.Update().Before("gorm:update").Register(PluginName+":before_update", p.before("update")),
+		cb.Update().After("gorm:update").Register(PluginName+":after_update", p.after("update")),
+		cb.Delete().Before("gorm:delete").Register(PluginName+":before_delete", p.before("delete")),
+		cb.Delete().After("gorm:delete").Register(PluginName+":after_delete", p.after("delete")),
+		cb.Row().Before("gorm:row").Register(PluginName+":before_row", p.before("row")),
+		cb.Row().After("gorm:row").Register(PluginName+":after_row", p.after("row")),
+		cb.Raw().Before("gorm:raw").Register(PluginName+":before_raw", p.before("raw")),
+		cb.Raw().After("gorm:raw").Register(PluginName+":after_raw", p.after("raw")),
+	)
+}
+
+// Describe implements prometheus.Collector.
+func (p *Plugin) Describe(ch chan<- *prometheus.Desc) {
+	if p.config.SkipMetrics {
+		return
+	}
+
+	p.durations.Describe(ch)
+	for _, c := range p.collectors {
+		c.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (p *Plugin) Collect(ch chan<- prometheus.Metric) {
+	if p.config.SkipMetrics {
+		return
+	}
+
+	p.durations.Collect(ch)
+	for _, c := range p.collectors {
+		c.Collect(ch)
+	}
+}
+
+// before records the start of a query and starts its span.
+func (p *Plugin) before(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		db.InstanceSet(startKey, time.Now())
+
+		if p.config.SkipTracing || db.Statement == nil || db.Statement.Context == nil {
+			return
+		}
+
+		ctx := db.Statement.Context
+		provider := p.config.TracerProvider
+		if parent := oteltrace.SpanFromContext(ctx); parent.SpanContext().IsValid() {
+			provider = parent.TracerProvider()
+		}
+		if provider == nil {
+			return
+		}
+
+		ctx, span := provider.Tracer(ScopeName).Start(ctx, "gorm."+operation,
+			oteltrace.WithSpanKind(oteltrace.SpanKindClient),
+			oteltrace.WithAttributes(
+				p.system,
+				semconv.DBName(p.config.DBName),
+				semconv.DBOperation(operation),
+			),
+		)
+
+		db.Statement.Context = ctx
+		db.InstanceSet(spanKey, span)
+	}
+}
+
+// after observes the duration of a query and ends its span.
+func (p *Plugin) after(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		failed := db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound)
+		table := db.Statement.Table
+
+		if start, ok := db.InstanceGet(startKey); ok && !p.config.SkipMetrics {
+			status := "ok"
+			if failed {
+				status = "error"
+			}
+			p.durations.WithLabelValues(operation, table, status).Observe(time.Since(start.(time.Time)).Seconds())
+		}
+
+		value, ok := db.InstanceGet(spanKey)
+		if !ok {
+			return
+		}
+		span := value.(oteltrace.Span)
+		defer span.End()
+
+		span.SetAttributes(
+			semconv.DBStatement(db.Statement.SQL.String()),
+			semconv.DBSQLTable(table),
+			attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+		)
+
+		if failed {
+			span.RecordError(db.Error)
+			span.SetStatus(codes.Error, db.Error.Error())
+		}
+	}
+}
+
+// dbSystem returns the database system attribute of a GORM dialector.
+func dbSystem(dialector string) attribute.KeyValue {
+	switch dialector {
+	case "postgres":
+		return semconv.DBSystemPostgreSQL
+	case "sqlite":
+		return semconv.DBSystemSqlite
+	default:
+		return semconv.DBSystemOtherSQL
+	}
+}
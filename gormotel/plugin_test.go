@@ -0,0 +1,109 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gormotel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type order struct {
+	ID   int
+	Name string
+}
+
+// newDryRunDB returns a database which generates the statements without executing them.
+func newDryRunDB(t *testing.T, plugin *Plugin) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(plugin))
+
+	return db
+}
+
+func TestPlugin(t *testing.T) {
+	tests := []struct {
+		name      string
+		withSpan  bool
+		wantSpans int
+	}{
+		{
+			name:      "ok: query is traced under the span of the request",
+			withSpan:  true,
+			wantSpans: 1,
+		},
+		{
+			name:      "ok: query is not traced without a span",
+			withSpan:  false,
+			wantSpans: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+			plugin := New(WithDBName("orders"))
+			db := newDryRunDB(t, plugin)
+
+			ctx := context.Background()
+			if tt.withSpan {
+				var span oteltrace.Span
+				ctx, span = provider.Tracer("test").Start(ctx, "request")
+				defer span.End()
+			}
+
+			var orders []order
+			require.NoError(t, db.WithContext(ctx).Where("name = ?", "secret").Find(&orders).Error)
+
+			spans := recorder.Ended()
+			require.Len(t, spans, tt.wantSpans)
+			if tt.wantSpans > 0 {
+				assert.Equal(t, "gorm.query", spans[0].Name())
+				assert.Contains(t, spans[0].Attributes(), attribute.String("db.sql.table", "orders"))
+				assert.Contains(t, spans[0].Attributes(), attribute.String("db.name", "orders"))
+				assert.Contains(t, spans[0].Attributes(), attribute.String("db.system", "postgresql"))
+			}
+
+			registry := prometheus.NewRegistry()
+			require.NoError(t, registry.Register(plugin))
+
+			count, err := testutil.GatherAndCount(registry, "gorm_query_duration_seconds")
+			require.NoError(t, err)
+			assert.Equal(t, 1, count)
+
+			count, err = testutil.GatherAndCount(registry, "go_sql_open_connections")
+			require.NoError(t, err)
+			assert.Equal(t, 1, count)
+		})
+	}
+}
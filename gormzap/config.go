@@ -0,0 +1,61 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gormzap
+
+import (
+	"time"
+
+	"gorm.io/gorm/logger"
+)
+
+type Option interface {
+	apply(*Config)
+}
+
+type optionFunc func(*Config)
+
+func (o optionFunc) apply(c *Config) {
+	o(c)
+}
+
+// Config contains the configuration of the Logger.
+type Config struct {
+	LogLevel      logger.LogLevel
+	SlowThreshold time.Duration
+	LogParameters bool
+}
+
+// WithLogLevel specifies the level of the logger. Defaults to the level of the environment type, see LevelFor.
+func WithLogLevel(level logger.LogLevel) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.LogLevel = level
+	})
+}
+
+// WithSlowThreshold specifies the duration above which a query is logged as slow. Defaults to 200ms, and a
+// zero duration disables the slow query logs.
+func WithSlowThreshold(threshold time.Duration) Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.SlowThreshold = threshold
+	})
+}
+
+// WithParameters logs the statements with the values of their parameters, which are redacted by default as
+// they may contain personal data or secrets.
+func WithParameters() Option {
+	return optionFunc(func(cfg *Config) {
+		cfg.LogParameters = true
+	})
+}
@@ -0,0 +1,190 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gormzap provides a GORM logger which writes structured logs to a zap.Logger, e.g. the one of
+// echozap.New.
+package gormzap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/ingka-group/fastecho/echozap"
+	"github.com/ingka-group/fastecho/env"
+)
+
+// DefaultSlowThreshold is the duration above which a query is logged as slow by default.
+const DefaultSlowThreshold = 200 * time.Millisecond
+
+// Logger is a GORM logger over a zap.Logger. Statements are logged at the debug level, slow queries at the
+// warn level and failed queries at the error level. The trace and span IDs of the context are attached to
+// the logs, when the context carries a span.
+type Logger struct {
+	config Config
+	logger *zap.Logger
+}
+
+var _ logger.Interface = (*Logger)(nil)
+
+// New creates a new Logger.
+func New(zapLogger *zap.Logger, options ...Option) *Logger {
+	config := Config{
+		LogLevel:      LevelFor(os.Getenv(echozap.EnvType)),
+		SlowThreshold: DefaultSlowThreshold,
+	}
+	for _, opt := range options {
+		opt.apply(&config)
+	}
+
+	return &Logger{
+		config: config,
+		logger: zapLogger,
+	}
+}
+
+// LevelFor returns the log level of an environment type: all statements are logged in the dev environment,
+// only slow and failed queries in the test and prod environments.
+func LevelFor(envType string) logger.LogLevel {
+	switch envType {
+	case echozap.TestEnv, echozap.ProdEnv:
+		return logger.Warn
+	default:
+		return logger.Info
+	}
+}
+
+// LogMode returns a copy of the logger with the given level.
+func (l *Logger) LogMode(level logger.LogLevel) logger.Interface {
+	clone := *l
+	clone.config.LogLevel = level
+
+	return &clone
+}
+
+// Info logs a message of GORM at the info level.
+func (l *Logger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.config.LogLevel >= logger.Info {
+		l.with(ctx).Info(fmt.Sprintf(msg, data...))
+	}
+}
+
+// Warn logs a message of GORM at the warn level.
+func (l *Logger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.config.LogLevel >= logger.Warn {
+		l.with(ctx).Warn(fmt.Sprintf(msg, data...))
+	}
+}
+
+// Error logs a message of GORM at the error level.
+func (l *Logger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.config.LogLevel >= logger.Error {
+		l.with(ctx).Error(fmt.Sprintf(msg, data...))
+	}
+}
+
+// Trace logs a query executed by GORM.
+func (l *Logger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.config.LogLevel <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	failed := err != nil && !errors.Is(err, gorm.ErrRecordNotFound)
+	slow := l.config.SlowThreshold > 0 && elapsed > l.config.SlowThreshold
+
+	switch {
+	case failed && l.config.LogLevel >= logger.Error:
+		l.with(ctx).Error("Query failed", append(l.queryFields(fc, elapsed), zap.Error(err))...)
+	case slow && l.config.LogLevel >= logger.Warn:
+		l.with(ctx).Warn("Slow query",
+			append(l.queryFields(fc, elapsed), zap.Duration("threshold", l.config.SlowThreshold))...,
+		)
+	case l.config.LogLevel >= logger.Info:
+		l.with(ctx).Debug("Query", l.queryFields(fc, elapsed)...)
+	}
+}
+
+// ParamsFilter redacts the parameters of the statements, unless they are logged.
+func (l *Logger) ParamsFilter(_ context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if l.config.LogParameters {
+		return sql, params
+	}
+
+	// the parameters are replaced rather than dropped, so that GORM renders the statement with all its
+	// placeholders
+	redacted := make([]interface{}, len(params))
+	for i := range params {
+		redacted[i] = env.Redacted
+	}
+
+	return sql, redacted
+}
+
+// queryFields returns the fields describing a query.
+func (l *Logger) queryFields(fc func() (string, int64), elapsed time.Duration) []zap.Field {
+	sql, rows := fc()
+
+	return []zap.Field{
+		zap.String("sql", sql),
+		zap.Int64("rows", rows),
+		zap.Duration("elapsed", elapsed),
+		zap.String("source", source()),
+	}
+}
+
+// packagePrefix prefixes the names of the functions of this package.
+var packagePrefix = reflect.TypeFor[Logger]().PkgPath() + "."
+
+// source returns the file and line of the caller of GORM which issued the query. Unlike utils.FileWithLineNum,
+// the frames of this package are skipped along with the ones of GORM.
+func source() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		frame, more := frames.Next()
+		internal := strings.HasPrefix(frame.Function, "gorm.io/") ||
+			strings.HasPrefix(frame.Function, packagePrefix) && !strings.HasSuffix(frame.File, "_test.go")
+		if !internal && !strings.HasSuffix(frame.File, ".gen.go") {
+			return frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// with returns the zap logger with the trace and span IDs of the context, if any.
+func (l *Logger) with(ctx context.Context) *zap.Logger {
+	spanCtx := oteltrace.SpanContextFromContext(ctx)
+	if !spanCtx.IsValid() {
+		return l.logger
+	}
+
+	return l.logger.With(
+		zap.String("trace_id", spanCtx.TraceID().String()),
+		zap.String("span_id", spanCtx.SpanID().String()),
+	)
+}
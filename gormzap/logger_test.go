@@ -0,0 +1,189 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gormzap
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/ingka-group/fastecho/echozap"
+)
+
+func TestLevelFor(t *testing.T) {
+	tests := []struct {
+		envType string
+		want    logger.LogLevel
+	}{
+		{envType: echozap.DevEnv, want: logger.Info},
+		{envType: echozap.TestEnv, want: logger.Warn},
+		{envType: echozap.ProdEnv, want: logger.Warn},
+		{envType: "", want: logger.Info},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.envType, func(t *testing.T) {
+			assert.Equal(t, tt.want, LevelFor(tt.envType))
+		})
+	}
+}
+
+func TestLogger_Trace(t *testing.T) {
+	tests := []struct {
+		name      string
+		options   []Option
+		elapsed   time.Duration
+		err       error
+		wantLevel zapcore.Level
+		wantMsg   string
+		wantLogs  int
+	}{
+		{
+			name:      "ok: statement is logged at the debug level",
+			options:   []Option{WithLogLevel(logger.Info)},
+			wantLevel: zap.DebugLevel,
+			wantMsg:   "Query",
+			wantLogs:  1,
+		},
+		{
+			name:     "ok: statement is not logged at the warn level",
+			options:  []Option{WithLogLevel(logger.Warn)},
+			wantLogs: 0,
+		},
+		{
+			name:      "ok: slow query is logged",
+			options:   []Option{WithLogLevel(logger.Warn), WithSlowThreshold(time.Millisecond)},
+			elapsed:   time.Second,
+			wantLevel: zap.WarnLevel,
+			wantMsg:   "Slow query",
+			wantLogs:  1,
+		},
+		{
+			name:     "ok: slow query logs are disabled",
+			options:  []Option{WithLogLevel(logger.Warn), WithSlowThreshold(0)},
+			elapsed:  time.Second,
+			wantLogs: 0,
+		},
+		{
+			name:      "ok: failed query is logged",
+			options:   []Option{WithLogLevel(logger.Error)},
+			err:       errors.New("connection refused"),
+			wantLevel: zap.ErrorLevel,
+			wantMsg:   "Query failed",
+			wantLogs:  1,
+		},
+		{
+			name:     "ok: record not found isn't a failure",
+			options:  []Option{WithLogLevel(logger.Error)},
+			err:      gorm.ErrRecordNotFound,
+			wantLogs: 0,
+		},
+		{
+			name:     "ok: nothing is logged when silent",
+			options:  []Option{WithLogLevel(logger.Silent)},
+			err:      errors.New("connection refused"),
+			wantLogs: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zap.DebugLevel)
+			l := New(zap.New(core), tt.options...)
+
+			l.Trace(context.Background(), time.Now().Add(-tt.elapsed), func() (string, int64) {
+				return "SELECT 1", 1
+			}, tt.err)
+
+			require.Equal(t, tt.wantLogs, logs.Len())
+			if tt.wantLogs > 0 {
+				entry := logs.All()[0]
+				assert.Equal(t, tt.wantLevel, entry.Level)
+				assert.Equal(t, tt.wantMsg, entry.Message)
+				assert.Equal(t, "SELECT 1", entry.ContextMap()["sql"])
+				assert.Contains(t, entry.ContextMap()["source"], "gormzap/logger_test.go:")
+			}
+		})
+	}
+}
+
+func TestLogger_Trace_traceIDs(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	l := New(zap.New(core), WithLogLevel(logger.Info))
+
+	spanCtx := oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID: oteltrace.TraceID{1},
+		SpanID:  oteltrace.SpanID{2},
+	})
+	ctx := oteltrace.ContextWithSpanContext(context.Background(), spanCtx)
+
+	l.Trace(ctx, time.Now(), func() (string, int64) { return "SELECT 1", 1 }, nil)
+
+	require.Equal(t, 1, logs.Len())
+	fields := logs.All()[0].ContextMap()
+	assert.Equal(t, spanCtx.TraceID().String(), fields["trace_id"])
+	assert.Equal(t, spanCtx.SpanID().String(), fields["span_id"])
+}
+
+func TestLogger_ParamsFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		want    string
+	}{
+		{
+			name: "ok: parameters are redacted",
+			want: `SELECT * FROM "users" WHERE password = '******'`,
+		},
+		{
+			name:    "ok: parameters are logged",
+			options: []Option{WithParameters()},
+			want:    `SELECT * FROM "users" WHERE password = 'secret'`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zap.DebugLevel)
+			l := New(zap.New(core), append(tt.options, WithLogLevel(logger.Info))...)
+
+			db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+				Logger:               l,
+				DryRun:               true,
+				DisableAutomaticPing: true,
+			})
+			require.NoError(t, err)
+
+			var users []map[string]any
+			require.NoError(t, db.Table("users").Where("password = ?", "secret").Find(&users).Error)
+
+			require.Equal(t, 1, logs.Len())
+			assert.Equal(t, tt.want, logs.All()[0].ContextMap()["sql"])
+			// the source is the caller of GORM, rather than GORM or the logger
+			assert.Contains(t, logs.All()[0].ContextMap()["source"], "gormzap/logger_test.go:")
+		})
+	}
+}
@@ -14,7 +14,19 @@
 
 package health
 
-import "gorm.io/gorm"
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+// Pinger is a connection to a database which can be checked, e.g. a *sql.DB.
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
 
 // checkDatabase pings the database and returns an error if it occurs.
 // If a database doesn't exist, the function returns no error.
@@ -30,3 +42,15 @@ func checkDatabase(db *gorm.DB) error {
 
 	return sqlDb.Ping()
 }
+
+// checkDatabases pings the named databases and returns the first error that occurs.
+func checkDatabases(ctx context.Context, dbs map[string]Pinger) error {
+	for _, name := range slices.Sorted(maps.Keys(dbs)) {
+		err := dbs[name].PingContext(ctx)
+		if err != nil {
+			return fmt.Errorf("database `%s` is down: %w", name, err)
+		}
+	}
+
+	return nil
+}
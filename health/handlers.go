@@ -23,16 +23,55 @@
 
 // Handler defines the http router implementation for health endpoints.
 type Handler struct {
-	db *gorm.DB
+	db        *gorm.DB
+	dbs       map[string]Pinger
+	databases func() map[string]Pinger
+	state     *ShutdownState
 }
 
 // NewHandler creates a new Handler for health endpoints.
 func NewHandler(db *gorm.DB) *Handler {
 	return &Handler{
-		db: db,
+		db:  db,
+		dbs: make(map[string]Pinger),
 	}
 }
 
+// WithShutdownState makes the readiness check fail once the given ShutdownState reports that the service
+// is shutting down.
+func (h *Handler) WithShutdownState(state *ShutdownState) *Handler {
+	h.state = state
+	return h
+}
+
+// AddDatabase adds a named database to the ones checked by the readiness endpoint.
+func (h *Handler) AddDatabase(name string, db Pinger) *Handler {
+	h.dbs[name] = db
+	return h
+}
+
+// WithDatabases adds the named databases returned by the given function to the ones checked by the readiness
+// endpoint. The function is called on every check, so that databases created later on are checked too.
+func (h *Handler) WithDatabases(dbs func() map[string]Pinger) *Handler {
+	h.databases = dbs
+	return h
+}
+
+// check checks all the databases of the handler.
+func (h *Handler) check(ctx echo.Context) error {
+	err := checkDatabase(h.db)
+	if err != nil {
+		return err
+	}
+
+	err = checkDatabases(ctx.Request().Context(), h.dbs)
+	if err != nil || h.databases == nil {
+		return err
+	}
+
+	return checkDatabases(ctx.Request().Context(), h.databases())
+}
+
 // Ready performs readiness check.
 //
 // @Summary Ready healthcheck
@@ -43,7 +82,11 @@ func NewHandler(db *gorm.DB) *Handler {
 // @Failure 503 {object} ServiceHealth "Service Unavailable"
 // @Router /health/ready [get]
 func (h *Handler) Ready(ctx echo.Context) error {
-	if checkDatabase(h.db) != nil {
+	if h.state.IsShuttingDown() {
+		return ctx.NoContent(http.StatusServiceUnavailable)
+	}
+
+	if h.check(ctx) != nil {
 		return ctx.NoContent(http.StatusServiceUnavailable)
 	}
 
@@ -61,7 +104,7 @@ func (h *Handler) Ready(ctx echo.Context) error {
 // @Failure 503 {object} ServiceHealth "Service Unavailable"
 // @Router /health/live [get]
 func (h *Handler) Live(ctx echo.Context) error {
-	if checkDatabase(h.db) != nil {
+	if h.check(ctx) != nil {
 		return ctx.JSON(http.StatusServiceUnavailable, ServiceHealth{
 			ServiceStatus: statusUnhealthy,
 			Description:   descriptionDatabaseIsDown,
@@ -0,0 +1,100 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type pingerFunc func(ctx context.Context) error
+
+func (f pingerFunc) PingContext(ctx context.Context) error {
+	return f(ctx)
+}
+
+func TestHandler(t *testing.T) {
+	up := pingerFunc(func(context.Context) error { return nil })
+	down := pingerFunc(func(context.Context) error { return errors.New("connection refused") })
+
+	tests := []struct {
+		name         string
+		db           Pinger
+		shuttingDown bool
+		expectReady  int
+		expectLive   int
+	}{
+		{
+			name:        "ok: healthy",
+			db:          up,
+			expectReady: http.StatusOK,
+			expectLive:  http.StatusOK,
+		},
+		{
+			name:        "ok: database down",
+			db:          down,
+			expectReady: http.StatusServiceUnavailable,
+			expectLive:  http.StatusServiceUnavailable,
+		},
+		{
+			name:         "ok: shutting down fails readiness only",
+			db:           up,
+			shuttingDown: true,
+			expectReady:  http.StatusServiceUnavailable,
+			expectLive:   http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := NewShutdownState()
+			if tt.shuttingDown {
+				state.Begin()
+			}
+
+			h := NewHandler(nil).WithShutdownState(state).AddDatabase("orders", tt.db)
+
+			assert.Equal(t, tt.expectReady, serve(h.Ready))
+			assert.Equal(t, tt.expectLive, serve(h.Live))
+		})
+	}
+}
+
+func TestHandler_WithDatabases(t *testing.T) {
+	dbs := map[string]Pinger{}
+	h := NewHandler(nil).WithDatabases(func() map[string]Pinger {
+		return dbs
+	})
+	assert.Equal(t, http.StatusOK, serve(h.Ready))
+
+	// databases added after the handler is created are checked too
+	dbs["orders"] = pingerFunc(func(context.Context) error { return errors.New("connection refused") })
+	assert.Equal(t, http.StatusServiceUnavailable, serve(h.Ready))
+}
+
+// serve returns the status of the response of the handler.
+func serve(handler echo.HandlerFunc) int {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	_ = handler(echo.New().NewContext(req, rec))
+
+	return rec.Code
+}
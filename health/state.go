@@ -0,0 +1,40 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package health
+
+import "sync/atomic"
+
+// ShutdownState tracks whether the service is shutting down.
+// It is shared between the server and the health handler, so that the readiness check starts failing
+// as soon as the shutdown begins.
+type ShutdownState struct {
+	shuttingDown atomic.Bool
+}
+
+// NewShutdownState creates a new ShutdownState.
+func NewShutdownState() *ShutdownState {
+	return &ShutdownState{}
+}
+
+// Begin marks the service as shutting down.
+func (s *ShutdownState) Begin() {
+	s.shuttingDown.Store(true)
+}
+
+// IsShuttingDown returns whether the shutdown of the service has begun.
+// A nil ShutdownState is never shutting down.
+func (s *ShutdownState) IsShuttingDown() bool {
+	return s != nil && s.shuttingDown.Load()
+}
@@ -0,0 +1,121 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fastecho
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+
+	"github.com/ingka-group/fastecho/gormotel"
+)
+
+// metricsRegistry returns the registerer and the gatherer of the given Prometheus registry or, if nil, the
+// default ones of Prometheus.
+func metricsRegistry(registry *prometheus.Registry) (prometheus.Registerer, prometheus.Gatherer) {
+	if registry == nil {
+		return prometheus.DefaultRegisterer, prometheus.DefaultGatherer
+	}
+
+	return registry, registry
+}
+
+// serverRegisterer is a Prometheus registerer which keeps track of the collectors registered through it, so that
+// they can be unregistered once the server is shut down. This lets a server be initialized again on the same
+// registry, e.g. the default one.
+type serverRegisterer struct {
+	prometheus.Registerer
+
+	mu         sync.Mutex
+	collectors []prometheus.Collector
+}
+
+// Register registers the collector and keeps track of it.
+func (r *serverRegisterer) Register(c prometheus.Collector) error {
+	err := r.Registerer.Register(c)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.collectors = append(r.collectors, c)
+
+	return nil
+}
+
+// MustRegister registers the collectors and keeps track of them, and panics if any of them fails to register.
+func (r *serverRegisterer) MustRegister(cs ...prometheus.Collector) {
+	for _, c := range cs {
+		if err := r.Register(c); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Unregister unregisters the collector and stops keeping track of it.
+func (r *serverRegisterer) Unregister(c prometheus.Collector) bool {
+	r.mu.Lock()
+	r.collectors = slices.DeleteFunc(r.collectors, func(collector prometheus.Collector) bool {
+		return collector == c
+	})
+	r.mu.Unlock()
+
+	return r.Registerer.Unregister(c)
+}
+
+// unregisterAll unregisters all the collectors registered through the registerer.
+func (r *serverRegisterer) unregisterAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, c := range r.collectors {
+		r.Registerer.Unregister(c)
+	}
+	r.collectors = nil
+}
+
+// dbCollector collects the metrics exported by the plugin registered by NewDB. The databases of the registry are
+// looked up on every collection, so that the ones created once the server is set up are collected too.
+type dbCollector struct {
+	db  *gorm.DB
+	dbs *Databases
+}
+
+// newDBCollector creates a collector of the given database and of the databases of the given registry, either
+// of which may be nil.
+func newDBCollector(db *gorm.DB, dbs *Databases) prometheus.Collector {
+	return &dbCollector{db: db, dbs: dbs}
+}
+
+// Describe sends no descriptions, which makes the collector unchecked, as the databases are not known upfront.
+func (c *dbCollector) Describe(chan<- *prometheus.Desc) {}
+
+// Collect collects the metrics of the databases.
+func (c *dbCollector) Collect(ch chan<- prometheus.Metric) {
+	dbs := c.dbs.all()
+	if c.db != nil && !slices.Contains(dbs, c.db) {
+		dbs = append(dbs, c.db)
+	}
+
+	for _, db := range dbs {
+		if collector, ok := db.Config.Plugins[gormotel.PluginName].(prometheus.Collector); ok {
+			collector.Collect(ch)
+		}
+	}
+}
@@ -0,0 +1,381 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fastecho
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pressly/goose/v3"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	// migrateCommand is the subcommand of the service which manages the migrations, e.g. `migrate status`.
+	migrateCommand = "migrate"
+
+	// migrationVersionFormat formats the version of a new migration as a timestamp, like goose.
+	migrationVersionFormat = "20060102150405"
+
+	migrationUpAnnotation = "-- +goose Up"
+	migrationStmtBegin    = "-- +goose StatementBegin"
+	migrationStmtEnd      = "-- +goose StatementEnd"
+
+	migrationUsage = "usage: migrate status|up|up-to <version>|down|redo|create <name>|validate"
+)
+
+// migrationTemplate is the content of a new migration.
+const migrationTemplate = `-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+`
+
+// migrationFilename matches the name of a SQL migration, e.g. `00001_create_orders.sql`.
+var migrationFilename = regexp.MustCompile(`^(\d+)_\w+\.sql$`)
+
+// Migrator manages the migrations of a database with goose. The results are reported to its logger.
+type Migrator struct {
+	provider *goose.Provider
+	db       *sql.DB
+	logger   *zap.Logger
+}
+
+// NewMigrator connects to the database configured by the `DB_*` environment variables, without applying
+// the migrations. The options select the migrations, the env prefix and the logger, like for NewDB.
+func NewMigrator(opts ...DBOption) (*Migrator, error) {
+	dbConf, o, err := newDBConfig(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	_, db, err := dbConf.open(&gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := newMigrationProvider(db, dbConf.driver(), o.migrations, o.migrationsDir)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &Migrator{
+		provider: provider,
+		db:       db,
+		logger:   o.logger,
+	}, nil
+}
+
+// Close closes the connection to the database.
+func (m *Migrator) Close() error {
+	return m.db.Close()
+}
+
+// Status reports the state of all the migrations.
+func (m *Migrator) Status(ctx context.Context) ([]*goose.MigrationStatus, error) {
+	statuses, err := m.provider.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, status := range statuses {
+		fields := []zap.Field{
+			zap.Int64("version", status.Source.Version),
+			zap.String("path", status.Source.Path),
+			zap.String("state", string(status.State)),
+		}
+		if status.State == goose.StateApplied {
+			fields = append(fields, zap.Time("applied_at", status.AppliedAt))
+		}
+
+		m.logger.Info("Migration status", fields...)
+	}
+
+	return statuses, nil
+}
+
+// Up applies all the pending migrations.
+func (m *Migrator) Up(ctx context.Context) error {
+	results, err := m.provider.Up(ctx)
+	if err != nil {
+		return err
+	}
+
+	return m.logResults(results...)
+}
+
+// UpTo applies the pending migrations up to and including the given version.
+func (m *Migrator) UpTo(ctx context.Context, version int64) error {
+	results, err := m.provider.UpTo(ctx, version)
+	if err != nil {
+		return err
+	}
+
+	return m.logResults(results...)
+}
+
+// Down rolls back the latest applied migration.
+func (m *Migrator) Down(ctx context.Context) error {
+	result, err := m.provider.Down(ctx)
+	if err != nil {
+		return err
+	}
+
+	return m.logResults(result)
+}
+
+// Redo rolls back the latest applied migration and applies it again.
+func (m *Migrator) Redo(ctx context.Context) error {
+	down, err := m.provider.Down(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = m.logResults(down)
+	if err != nil {
+		return err
+	}
+
+	up, err := m.provider.ApplyVersion(ctx, down.Source.Version, true)
+	if err != nil {
+		return err
+	}
+
+	return m.logResults(up)
+}
+
+// logResults reports the results of the migrations.
+func (m *Migrator) logResults(results ...*goose.MigrationResult) error {
+	if len(results) == 0 {
+		m.logger.Info("No migrations to apply")
+		return nil
+	}
+
+	return logMigrationResults(m.logger, results...)
+}
+
+// CreateMigration writes a new SQL migration with the given name to the directory on disk, and returns its path.
+// The version of the migration is the current UTC timestamp.
+func CreateMigration(dir, name string) (string, error) {
+	name = strings.ToLower(strings.Join(strings.Fields(name), "_"))
+	if name == "" {
+		return "", errors.New("the name of the migration must not be empty")
+	}
+
+	version := time.Now().UTC().Format(migrationVersionFormat)
+	path := filepath.Join(dir, fmt.Sprintf("%s_%s.sql", version, name))
+
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return "", fmt.Errorf("failed to create migration: %w", err)
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(migrationTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// ValidateMigrations checks the SQL migrations in the given directory of the file system without connecting to
+// the database. Each migration must be named `<version>_<name>.sql` with a unique version, have an Up section
+// and balanced statement blocks.
+func ValidateMigrations(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	versions := make(map[int64]string)
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		matches := migrationFilename.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			problems = append(problems, fmt.Sprintf("migration `%s` must be named `<version>_<name>.sql`", entry.Name()))
+			continue
+		}
+
+		version, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil || version < 1 {
+			problems = append(problems, fmt.Sprintf("migration `%s` has an invalid version", entry.Name()))
+			continue
+		}
+		if other, ok := versions[version]; ok {
+			problems = append(problems, fmt.Sprintf("migrations `%s` and `%s` have the same version", other, entry.Name()))
+		}
+		versions[version] = entry.Name()
+
+		problems = append(problems, validateMigration(fsys, path.Join(dir, entry.Name()))...)
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// validateMigration checks the annotations of a SQL migration.
+func validateMigration(fsys fs.FS, name string) []string {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	defer f.Close()
+
+	var hasUp bool
+	var openStmts int
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		switch strings.TrimSpace(scanner.Text()) {
+		case migrationUpAnnotation:
+			hasUp = true
+		case migrationStmtBegin:
+			openStmts++
+		case migrationStmtEnd:
+			openStmts--
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return []string{err.Error()}
+	}
+
+	var problems []string
+	if !hasUp {
+		problems = append(problems, fmt.Sprintf("migration `%s` has no `%s` annotation", name, migrationUpAnnotation))
+	}
+	if openStmts != 0 {
+		problems = append(problems, fmt.Sprintf("migration `%s` has unbalanced statement blocks", name))
+	}
+
+	return problems
+}
+
+// Migrate runs a migration command given its arguments, e.g. `status` or `up-to 20240101120000`:
+//
+//   - status: reports the state of all the migrations
+//   - up: applies all the pending migrations
+//   - up-to <version>: applies the pending migrations up to and including the version
+//   - down: rolls back the latest applied migration
+//   - redo: rolls back the latest applied migration and applies it again
+//   - create <name>: writes a new SQL migration to the migrations directory on disk
+//   - validate: checks the migrations without connecting to the database
+//
+// The options select the database and the migrations, like for NewDB.
+func Migrate(ctx context.Context, args []string, opts ...DBOption) error {
+	if len(args) == 0 {
+		return errors.New(migrationUsage)
+	}
+
+	o, err := newDBOptions(opts...)
+	if err != nil {
+		return err
+	}
+
+	switch command := args[0]; command {
+	case "create":
+		if len(args) < 2 {
+			return errors.New(migrationUsage)
+		}
+
+		path, err := CreateMigration(o.migrationsDir, strings.Join(args[1:], " "))
+		if err != nil {
+			return err
+		}
+
+		o.logger.Info("Migration created", zap.String("path", path))
+
+		return nil
+	case "validate":
+		err = ValidateMigrations(o.migrations, o.migrationsDir)
+		if err != nil {
+			return err
+		}
+
+		o.logger.Info("Migrations are valid")
+
+		return nil
+	case "status", "up", "up-to", "down", "redo":
+		m, err := NewMigrator(append(opts, WithLogger(o.logger))...)
+		if err != nil {
+			return err
+		}
+		defer m.Close()
+
+		return m.run(ctx, command, args[1:])
+	default:
+		return fmt.Errorf("unknown migration command `%s`, %s", command, migrationUsage)
+	}
+}
+
+// run runs a migration command which requires the database.
+func (m *Migrator) run(ctx context.Context, command string, args []string) error {
+	switch command {
+	case "status":
+		_, err := m.Status(ctx)
+		return err
+	case "up":
+		return m.Up(ctx)
+	case "up-to":
+		if len(args) == 0 {
+			return errors.New(migrationUsage)
+		}
+
+		version, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid migration version `%s`", args[0])
+		}
+
+		return m.UpTo(ctx, version)
+	case "down":
+		return m.Down(ctx)
+	case "redo":
+		return m.Redo(ctx)
+	default:
+		return fmt.Errorf("unknown migration command `%s`, %s", command, migrationUsage)
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fastecho
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"testing/fstest"
+
+	"github.com/pressly/goose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/ingka-group/fastecho/env"
+)
+
+func TestValidateMigrations(t *testing.T) {
+	valid := "-- +goose Up\n-- +goose StatementBegin\nSELECT 1;\n-- +goose StatementEnd\n\n-- +goose Down\nSELECT 1;\n"
+
+	tests := []struct {
+		name      string
+		fsys      fstest.MapFS
+		expectErr string
+	}{
+		{
+			name: "ok: valid migrations",
+			fsys: fstest.MapFS{
+				"migrations/00001_create_orders.sql": {Data: []byte(valid)},
+				"migrations/00002_add_status.sql":    {Data: []byte(valid)},
+				"migrations/README.md":               {Data: []byte("# Migrations")},
+			},
+		},
+		{
+			name: "error: invalid name",
+			fsys: fstest.MapFS{
+				"migrations/create_orders.sql": {Data: []byte(valid)},
+			},
+			expectErr: "must be named",
+		},
+		{
+			name: "error: duplicate version",
+			fsys: fstest.MapFS{
+				"migrations/00001_create_orders.sql": {Data: []byte(valid)},
+				"migrations/1_add_status.sql":        {Data: []byte(valid)},
+			},
+			expectErr: "have the same version",
+		},
+		{
+			name: "error: missing up annotation",
+			fsys: fstest.MapFS{
+				"migrations/00001_create_orders.sql": {Data: []byte("SELECT 1;\n")},
+			},
+			expectErr: "has no `-- +goose Up` annotation",
+		},
+		{
+			name: "error: unbalanced statement blocks",
+			fsys: fstest.MapFS{
+				"migrations/00001_create_orders.sql": {Data: []byte("-- +goose Up\n-- +goose StatementBegin\nSELECT 1;\n")},
+			},
+			expectErr: "unbalanced statement blocks",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMigrations(tt.fsys, "migrations")
+			if tt.expectErr != "" {
+				assert.ErrorContains(t, err, tt.expectErr)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestCreateMigration(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "migrations")
+
+	path, err := CreateMigration(dir, "Create orders")
+	require.NoError(t, err)
+
+	assert.Regexp(t, regexp.MustCompile(`^\d{14}_create_orders\.sql$`), filepath.Base(path))
+	assert.NoError(t, ValidateMigrations(os.DirFS(dir), "."))
+
+	_, err = CreateMigration(dir, " ")
+	assert.Error(t, err)
+}
+
+func TestMigrate(t *testing.T) {
+	migrations := fstest.MapFS{
+		"migrations/00001_create_orders.sql": {Data: []byte("-- +goose Up\nCREATE TABLE orders (id INTEGER);\n\n-- +goose Down\nDROP TABLE orders;\n")},
+		"migrations/00002_create_stock.sql":  {Data: []byte("-- +goose Up\nCREATE TABLE stock (id INTEGER);\n\n-- +goose Down\nDROP TABLE stock;\n")},
+		"migrations/00003_create_items.sql":  {Data: []byte("-- +goose Up\nCREATE TABLE items (id INTEGER);\n\n-- +goose Down\nDROP TABLE items;\n")},
+	}
+
+	tests := []struct {
+		name          string
+		given         [][]string // the commands run beforehand
+		args          []string
+		expectApplied []int64
+		expectLogs    []string // the directions of the migrations applied by the command, or the states of the migrations
+		expectErr     string
+	}{
+		{
+			name:          "ok: status",
+			given:         [][]string{{"up-to", "1"}},
+			args:          []string{"status"},
+			expectApplied: []int64{1},
+			expectLogs:    []string{"applied", "pending", "pending"},
+		},
+		{
+			name:          "ok: up-to",
+			args:          []string{"up-to", "2"},
+			expectApplied: []int64{1, 2},
+			expectLogs:    []string{"up", "up"},
+		},
+		{
+			name:          "ok: down",
+			given:         [][]string{{"up"}},
+			args:          []string{"down"},
+			expectApplied: []int64{1, 2},
+			expectLogs:    []string{"down"},
+		},
+		{
+			name:          "ok: redo",
+			given:         [][]string{{"up"}},
+			args:          []string{"redo"},
+			expectApplied: []int64{1, 2, 3},
+			expectLogs:    []string{"down", "up"},
+		},
+		{
+			name:      "error: no command",
+			args:      []string{},
+			expectErr: migrationUsage,
+		},
+		{
+			name:      "error: unknown command",
+			args:      []string{"reset"},
+			expectErr: "unknown migration command `reset`",
+		},
+		{
+			name:      "error: up-to without version",
+			args:      []string{"up-to"},
+			expectErr: migrationUsage,
+		},
+		{
+			name:      "error: up-to with invalid version",
+			args:      []string{"up-to", "latest"},
+			expectErr: "invalid migration version `latest`",
+		},
+		{
+			name:      "error: down without applied migrations",
+			args:      []string{"down"},
+			expectErr: "no next version found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// each command connects again, so the database is a file rather than in memory
+			sources := env.Vars("test", map[string]string{
+				dbDriverName: DriverSQLite,
+				dbName:       filepath.Join(t.TempDir(), "test.db"),
+			})
+			opts := []DBOption{WithSources(sources), WithMigrations(migrations, "migrations")}
+
+			for _, args := range tt.given {
+				require.NoError(t, Migrate(context.Background(), args, append(opts, WithLogger(zap.NewNop()))...))
+			}
+
+			core, logs := observer.New(zap.InfoLevel)
+			err := Migrate(context.Background(), tt.args, append(opts, WithLogger(zap.New(core)))...)
+			if tt.expectErr != "" {
+				assert.ErrorContains(t, err, tt.expectErr)
+				return
+			}
+			require.NoError(t, err)
+
+			var got []string
+			for _, entry := range logs.All() {
+				for _, key := range []string{"direction", "state"} {
+					if value, ok := entry.ContextMap()[key]; ok {
+						got = append(got, value.(string))
+					}
+				}
+			}
+			assert.Equal(t, tt.expectLogs, got)
+
+			m, err := NewMigrator(append(opts, WithLogger(zap.NewNop()))...)
+			require.NoError(t, err)
+			defer m.Close()
+
+			statuses, err := m.Status(context.Background())
+			require.NoError(t, err)
+
+			var applied []int64
+			for _, status := range statuses {
+				if status.State == goose.StateApplied {
+					applied = append(applied, status.Source.Version)
+				}
+			}
+			assert.Equal(t, tt.expectApplied, applied)
+		})
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fastecho
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/ingka-group/fastecho/errs"
+)
+
+// Names of the built-in middlewares.
+const (
+	MiddlewareRecover   = "recover"
+	MiddlewareTracing   = "tracing"
+	MiddlewareRequestID = "request_id"
+	MiddlewareLogger    = "logger"
+	MiddlewareContext   = "context"
+	MiddlewareGzip      = "gzip"
+	MiddlewareMetrics   = "metrics"
+	MiddlewareTx        = "transaction"
+)
+
+// Middleware is a named middleware of the Pipeline.
+type Middleware struct {
+	Name string
+	Func echo.MiddlewareFunc
+}
+
+// Pipeline is the ordered list of middlewares of the server. Each middleware wraps the ones that follow it,
+// i.e. the first middleware is the outermost one.
+type Pipeline struct {
+	middlewares []Middleware
+}
+
+// Names returns the names of the middlewares in order.
+func (p *Pipeline) Names() []string {
+	names := make([]string, 0, len(p.middlewares))
+	for _, m := range p.middlewares {
+		names = append(names, m.Name)
+	}
+
+	return names
+}
+
+// Append adds middlewares to the end of the Pipeline.
+func (p *Pipeline) Append(middlewares ...Middleware) error {
+	return p.insert(len(p.middlewares), middlewares...)
+}
+
+// Prepend adds middlewares to the beginning of the Pipeline.
+func (p *Pipeline) Prepend(middlewares ...Middleware) error {
+	return p.insert(0, middlewares...)
+}
+
+// InsertBefore adds middlewares right before the middleware with the given name.
+func (p *Pipeline) InsertBefore(name string, middlewares ...Middleware) error {
+	i, err := p.index(name)
+	if err != nil {
+		return err
+	}
+
+	return p.insert(i, middlewares...)
+}
+
+// InsertAfter adds middlewares right after the middleware with the given name.
+func (p *Pipeline) InsertAfter(name string, middlewares ...Middleware) error {
+	i, err := p.index(name)
+	if err != nil {
+		return err
+	}
+
+	return p.insert(i+1, middlewares...)
+}
+
+// Replace replaces the function of the middleware with the given name, keeping its position.
+func (p *Pipeline) Replace(name string, fn echo.MiddlewareFunc) error {
+	i, err := p.index(name)
+	if err != nil {
+		return err
+	}
+	if fn == nil {
+		return errs.New(fmt.Sprintf("middleware `%s` has no function", name))
+	}
+
+	p.middlewares[i].Func = fn
+	return nil
+}
+
+// Remove disables the middleware with the given name.
+func (p *Pipeline) Remove(name string) error {
+	i, err := p.index(name)
+	if err != nil {
+		return err
+	}
+
+	p.middlewares = slices.Delete(p.middlewares, i, i+1)
+	return nil
+}
+
+// MoveBefore moves the middleware with the given name right before the target middleware.
+func (p *Pipeline) MoveBefore(name, target string) error {
+	m, err := p.take(name, target)
+	if err != nil {
+		return err
+	}
+
+	return p.InsertBefore(target, m)
+}
+
+// MoveAfter moves the middleware with the given name right after the target middleware.
+func (p *Pipeline) MoveAfter(name, target string) error {
+	m, err := p.take(name, target)
+	if err != nil {
+		return err
+	}
+
+	return p.InsertAfter(target, m)
+}
+
+// use registers the middlewares of the Pipeline to Echo.
+func (p *Pipeline) use(e *echo.Echo) {
+	for _, m := range p.middlewares {
+		e.Use(m.Func)
+	}
+}
+
+// insert adds middlewares at the given position. Names must be unique within the Pipeline.
+func (p *Pipeline) insert(i int, middlewares ...Middleware) error {
+	for _, m := range middlewares {
+		if m.Func == nil {
+			return errs.New(fmt.Sprintf("middleware `%s` has no function", m.Name))
+		}
+		if slices.Contains(p.Names(), m.Name) {
+			return errs.New(fmt.Sprintf("middleware `%s` already exists in the pipeline", m.Name))
+		}
+	}
+
+	p.middlewares = slices.Insert(p.middlewares, i, middlewares...)
+	return nil
+}
+
+// take removes the middleware with the given name and returns it, once the target is known to exist.
+func (p *Pipeline) take(name, target string) (Middleware, error) {
+	if name == target {
+		return Middleware{}, errs.New(fmt.Sprintf("middleware `%s` cannot be moved relative to itself", name))
+	}
+	if _, err := p.index(target); err != nil {
+		return Middleware{}, err
+	}
+
+	i, err := p.index(name)
+	if err != nil {
+		return Middleware{}, err
+	}
+
+	m := p.middlewares[i]
+	p.middlewares = slices.Delete(p.middlewares, i, i+1)
+
+	return m, nil
+}
+
+// index returns the position of the middleware with the given name.
+func (p *Pipeline) index(name string) (int, error) {
+	i := slices.IndexFunc(p.middlewares, func(m Middleware) bool {
+		return m.Name == name
+	})
+	if i < 0 {
+		return -1, errs.New(fmt.Sprintf("middleware `%s` not found in the pipeline", name))
+	}
+
+	return i, nil
+}
@@ -0,0 +1,146 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fastecho
+
+import (
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPipeline(t *testing.T) {
+	noop := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return next
+	}
+
+	newPipeline := func() *Pipeline {
+		p := &Pipeline{}
+		_ = p.Append(
+			Middleware{Name: MiddlewareRecover, Func: noop},
+			Middleware{Name: MiddlewareLogger, Func: noop},
+			Middleware{Name: MiddlewareContext, Func: noop},
+		)
+		return p
+	}
+
+	tests := []struct {
+		name      string
+		given     func(p *Pipeline) error
+		expect    []string
+		expectErr bool
+	}{
+		{
+			name: "ok: insert before",
+			given: func(p *Pipeline) error {
+				return p.InsertBefore(MiddlewareContext, Middleware{Name: "auth", Func: noop})
+			},
+			expect: []string{MiddlewareRecover, MiddlewareLogger, "auth", MiddlewareContext},
+		},
+		{
+			name: "ok: insert after",
+			given: func(p *Pipeline) error {
+				return p.InsertAfter(MiddlewareContext, Middleware{Name: "auth", Func: noop})
+			},
+			expect: []string{MiddlewareRecover, MiddlewareLogger, MiddlewareContext, "auth"},
+		},
+		{
+			name: "ok: remove",
+			given: func(p *Pipeline) error {
+				return p.Remove(MiddlewareLogger)
+			},
+			expect: []string{MiddlewareRecover, MiddlewareContext},
+		},
+		{
+			name: "ok: move before",
+			given: func(p *Pipeline) error {
+				return p.MoveBefore(MiddlewareContext, MiddlewareRecover)
+			},
+			expect: []string{MiddlewareContext, MiddlewareRecover, MiddlewareLogger},
+		},
+		{
+			name: "ok: move after",
+			given: func(p *Pipeline) error {
+				return p.MoveAfter(MiddlewareRecover, MiddlewareContext)
+			},
+			expect: []string{MiddlewareLogger, MiddlewareContext, MiddlewareRecover},
+		},
+		{
+			name: "error: unknown middleware",
+			given: func(p *Pipeline) error {
+				return p.Replace("unknown", noop)
+			},
+			expect:    []string{MiddlewareRecover, MiddlewareLogger, MiddlewareContext},
+			expectErr: true,
+		},
+		{
+			name: "error: duplicate name",
+			given: func(p *Pipeline) error {
+				return p.Append(Middleware{Name: MiddlewareLogger, Func: noop})
+			},
+			expect:    []string{MiddlewareRecover, MiddlewareLogger, MiddlewareContext},
+			expectErr: true,
+		},
+		{
+			name: "error: move to unknown target",
+			given: func(p *Pipeline) error {
+				return p.MoveAfter(MiddlewareRecover, "unknown")
+			},
+			expect:    []string{MiddlewareRecover, MiddlewareLogger, MiddlewareContext},
+			expectErr: true,
+		},
+		{
+			name: "error: move before itself",
+			given: func(p *Pipeline) error {
+				return p.MoveBefore(MiddlewareLogger, MiddlewareLogger)
+			},
+			expect:    []string{MiddlewareRecover, MiddlewareLogger, MiddlewareContext},
+			expectErr: true,
+		},
+		{
+			name: "error: move after itself",
+			given: func(p *Pipeline) error {
+				return p.MoveAfter(MiddlewareLogger, MiddlewareLogger)
+			},
+			expect:    []string{MiddlewareRecover, MiddlewareLogger, MiddlewareContext},
+			expectErr: true,
+		},
+		{
+			name: "error: replace with nil function",
+			given: func(p *Pipeline) error {
+				return p.Replace(MiddlewareLogger, nil)
+			},
+			expect:    []string{MiddlewareRecover, MiddlewareLogger, MiddlewareContext},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newPipeline()
+
+			err := tt.given(p)
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expect, p.Names())
+			for _, m := range p.middlewares {
+				assert.NotNil(t, m.Func, m.Name)
+			}
+		})
+	}
+}
@@ -20,9 +20,11 @@
 
 	"github.com/labstack/echo-contrib/echoprometheus"
 	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
 	swguicdn "github.com/swaggest/swgui/v5cdn"
 	"gorm.io/gorm"
 
+	"github.com/ingka-group/fastecho/env"
 	"github.com/ingka-group/fastecho/errs"
 	"github.com/ingka-group/fastecho/health"
 )
@@ -30,15 +32,23 @@
 // Router contains all the available routes of the service.
 type Router struct {
 	Routes []Route
+	// SkipList contains the routes skipped by the built-in middlewares
+	SkipList *SkipList
 }
 
 // Config contains the configuration for the router.
 type Config struct {
 	Echo             *echo.Echo
+	AdminEcho        *echo.Echo // hosts the metrics, health checks and swagger endpoints instead of Echo, if defined
 	Routes           func(e *echo.Echo, r *Router) error
 	SkipMetrics      bool
+	MetricsGatherer  prometheus.Gatherer // served on the metrics endpoint, defaults to the Prometheus default gatherer
 	SkipHealthChecks bool
 	HealthChecksDB   *gorm.DB
+	HealthChecksDBs  func() map[string]health.Pinger // returns the named databases checked in addition to HealthChecksDB
+	ShutdownState    *health.ShutdownState
+	SkipList         *SkipList
+	ConfigEnvs       func() env.Map // returns the variables served with secrets redacted on the config endpoint of AdminEcho, if defined
 	SwaggerTitle     string
 	SwaggerPath      string
 }
@@ -54,22 +64,37 @@ type Route struct {
 // NewRouter creates a new Router.
 func NewRouter(cfg Config) (*Router, error) {
 	r := &Router{
-		Routes: make([]Route, 0),
+		Routes:   make([]Route, 0),
+		SkipList: cfg.SkipList,
+	}
+	if r.SkipList == nil {
+		r.SkipList = NewSkipList()
+	}
+
+	// operational endpoints are hosted by the admin server, if there is one.
+	// Otherwise, they are skipped by the middlewares of the main server.
+	opsEcho := cfg.Echo
+	if cfg.AdminEcho != nil {
+		opsEcho = cfg.AdminEcho
+	} else {
+		r.SkipList.Add(healthReadyPath, healthLivePath, metricsPath, swaggerPath, swaggerJSONPath)
 	}
 
 	if !cfg.SkipHealthChecks {
-		healthHandler := health.NewHandler(cfg.HealthChecksDB)
+		healthHandler := health.NewHandler(cfg.HealthChecksDB).
+			WithShutdownState(cfg.ShutdownState).
+			WithDatabases(cfg.HealthChecksDBs)
 
 		r.Routes = append(r.Routes, Route{
-			path:        "/health/ready",
-			group:       cfg.Echo.Group(""),
+			path:        healthReadyPath,
+			group:       opsEcho.Group(""),
 			handlerFunc: healthHandler.Ready,
 			restVerb:    http.MethodGet,
 		})
 
 		r.Routes = append(r.Routes, Route{
-			path:        "/health/live",
-			group:       cfg.Echo.Group(""),
+			path:        healthLivePath,
+			group:       opsEcho.Group(""),
 			handlerFunc: healthHandler.Live,
 			restVerb:    http.MethodGet,
 		})
@@ -84,10 +109,18 @@ func NewRouter(cfg Config) (*Router, error) {
 	}
 
 	if !cfg.SkipMetrics {
-		r.addMetrics(cfg.Echo)
+		r.addMetrics(opsEcho, cfg.MetricsGatherer)
 	}
 
-	r.addSwagger(cfg.Echo, cfg.SwaggerTitle, cfg.SwaggerPath)
+	r.addSwagger(opsEcho, cfg.SwaggerTitle, cfg.SwaggerPath)
+
+	// the configuration is never exposed by the main server
+	if cfg.ConfigEnvs != nil {
+		if cfg.AdminEcho == nil {
+			return nil, errs.New("the config endpoint requires the admin server")
+		}
+		r.addConfig(cfg.AdminEcho, cfg.ConfigEnvs)
+	}
 
 	return r, nil
 }
@@ -104,25 +137,39 @@ func AddRoute(r *Router, group *echo.Group, path string, handlerFunc echo.Handle
 	return r
 }
 
-// addMetrics adds a handler for metrics.
-func (r *Router) addMetrics(e *echo.Echo) *Router {
-	e.GET("/metrics", echoprometheus.NewHandler())
+// addMetrics adds a handler for the metrics of the given gatherer, or of the default one if nil.
+func (r *Router) addMetrics(e *echo.Echo, gatherer prometheus.Gatherer) *Router {
+	e.GET(metricsPath, echoprometheus.NewHandlerWithConfig(echoprometheus.HandlerConfig{Gatherer: gatherer}))
 	return r
 }
 
 const (
-	swaggerPath = "/swagger"
+	healthReadyPath = "/health/ready"
+	healthLivePath  = "/health/live"
+	metricsPath     = "/metrics"
+	swaggerPath     = "/swagger"
+	swaggerJSONPath = "/swagger/swagger.json"
+	configPath      = "/config"
 )
 
 // addSwagger adds a handler for swagger documentation to the given route.
 func (r *Router) addSwagger(e *echo.Echo, title, path string) *Router {
 	// Register the swagger.json to the server as a static resource
-	e.File("swagger/swagger.json", "api/swagger.json")
+	e.File(swaggerJSONPath, "api/swagger.json")
 
 	e.GET(swaggerPath, serveSwaggerUI(title, path))
 	return r
 }
 
+// addConfig adds a handler for the effective configuration of the service.
+// The values of secrets are redacted, and each value comes with the source it was read from.
+func (r *Router) addConfig(e *echo.Echo, envs func() env.Map) *Router {
+	e.GET(configPath, func(c echo.Context) error {
+		return c.JSON(http.StatusOK, envs())
+	})
+	return r
+}
+
 // Setup configures the routes for echo.
 func (r *Router) Setup() error {
 	// register routes to echo
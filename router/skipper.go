@@ -0,0 +1,71 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package router
+
+import (
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+// SkipList is a registry of routes that are skipped by the built-in middlewares, such as tracing,
+// request IDs and access logging.
+//
+// Entries are matched exactly against the route of the request as returned by echo.Context.Path(),
+// e.g. `/health/ready` or `/v1/orders/:id`. A business route such as `/v1/orders/health/summary`
+// is not skipped because of an entry for `/health`.
+type SkipList struct {
+	mu     sync.RWMutex
+	routes map[string]struct{}
+}
+
+// NewSkipList creates a new SkipList with the given routes.
+func NewSkipList(routes ...string) *SkipList {
+	s := &SkipList{
+		routes: make(map[string]struct{}),
+	}
+	s.Add(routes...)
+
+	return s
+}
+
+// Add adds routes to the SkipList.
+func (s *SkipList) Add(routes ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, route := range routes {
+		s.routes[route] = struct{}{}
+	}
+}
+
+// Contains returns whether the given route is in the SkipList.
+func (s *SkipList) Contains(route string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.routes[route]
+	return ok
+}
+
+// Skip returns whether the request should be skipped by the middlewares. It can be used as a middleware.Skipper.
+func (s *SkipList) Skip(ctx echo.Context) bool {
+	path := ctx.Path()
+	if path == "" {
+		return false
+	}
+
+	return s.Contains(path)
+}
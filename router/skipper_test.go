@@ -0,0 +1,75 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSkipList_Skip(t *testing.T) {
+	tests := []struct {
+		name   string
+		route  string
+		target string
+		expect bool
+	}{
+		{
+			name:   "ok: exact path",
+			route:  "/health/ready",
+			target: "/health/ready",
+			expect: true,
+		},
+		{
+			name:   "ok: route pattern",
+			route:  "/v1/internal/:id",
+			target: "/v1/internal/42",
+			expect: true,
+		},
+		{
+			name:   "ok: business route containing a skipped path",
+			route:  "/v1/orders/health/summary",
+			target: "/v1/orders/health/summary",
+			expect: false,
+		},
+		{
+			name:   "ok: unknown route",
+			route:  "/v1/orders",
+			target: "/metrics",
+			expect: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			skipList := NewSkipList("/health/ready", "/metrics", "/v1/internal/:id")
+
+			var skipped bool
+			e := echo.New()
+			e.GET(tt.route, func(c echo.Context) error {
+				skipped = skipList.Skip(c)
+				return c.NoContent(http.StatusOK)
+			})
+
+			e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			assert.Equal(t, tt.expect, skipped)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sqlite registers a pure-Go SQLite driver, without cgo, for the databases created by fastecho.NewDB,
+// e.g. for tests and local runs. It is enabled by importing the package for its side effects:
+//
+//	import _ "github.com/ingka-group/fastecho/sqlite"
+//
+// and setting DB_DRIVER to `sqlite`. DB_NAME is then the path of the database file, or `:memory:` for an
+// in-memory database.
+package sqlite
+
+import (
+	"database/sql"
+	"net/url"
+
+	"github.com/glebarez/sqlite"
+	"github.com/pressly/goose/v3"
+	"gorm.io/gorm"
+
+	"github.com/ingka-group/fastecho/dbdriver"
+)
+
+// DriverName is the value of DB_DRIVER selecting SQLite.
+const DriverName = "sqlite"
+
+func init() {
+	dbdriver.Register(DriverName, dbdriver.Driver{
+		SQLDriver:        sqlite.DriverName,
+		DSN:              dsn,
+		Dialector:        dialector,
+		MigrationDialect: goose.DialectSQLite3,
+	})
+}
+
+// dsn returns the path of the database with foreign keys enforced, as they are by Postgres.
+func dsn(name string) string {
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "busy_timeout(5000)")
+
+	return "file:" + name + "?" + params.Encode()
+}
+
+// dialector returns the GORM dialector over a connection.
+func dialector(conn *sql.DB) gorm.Dialector {
+	return &sqlite.Dialector{Conn: conn}
+}
@@ -0,0 +1,43 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlite
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/ingka-group/fastecho/dbdriver"
+)
+
+func TestDriver(t *testing.T) {
+	driver, ok := dbdriver.Lookup(DriverName)
+	require.True(t, ok)
+
+	conn, err := sql.Open(driver.SQLDriver, driver.DSN(":memory:"))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	db, err := gorm.Open(driver.Dialector(conn), &gorm.Config{})
+	require.NoError(t, err)
+
+	// foreign keys are enforced
+	var enabled int
+	require.NoError(t, db.Raw("PRAGMA foreign_keys").Scan(&enabled).Error)
+	assert.Equal(t, 1, enabled)
+}
@@ -15,6 +15,8 @@
 package stringutils
 
 import (
+	"fmt"
+	"math"
 	"strconv"
 	"strings"
 )
@@ -43,3 +45,50 @@ func ExistsInSlice(str string, sl []string) bool {
 	}
 	return false
 }
+
+// byteSizeUnits maps the units of byte sizes to their multiplier
+var byteSizeUnits = map[string]int64{
+	"":    1,
+	"B":   1,
+	"KB":  1000,
+	"MB":  1000 * 1000,
+	"GB":  1000 * 1000 * 1000,
+	"TB":  1000 * 1000 * 1000 * 1000,
+	"KIB": 1 << 10,
+	"MIB": 1 << 20,
+	"GIB": 1 << 30,
+	"TIB": 1 << 40,
+}
+
+// ToByteSize converts a string such as `512`, `10KB` or `1GiB` to a number of bytes.
+// It returns strconv.ErrRange when the size exceeds math.MaxInt64.
+func ToByteSize(str string) (int64, error) {
+	str = strings.TrimSpace(str)
+
+	i := strings.IndexFunc(str, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(str)
+	}
+
+	number, unit := str[:i], strings.ToUpper(strings.TrimSpace(str[i:]))
+
+	multiplier, ok := byteSizeUnits[unit]
+	if !ok {
+		return -1, fmt.Errorf("unknown byte size unit: %s", unit)
+	}
+
+	num, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return -1, err
+	}
+
+	// float64(math.MaxInt64) rounds up to 2^63, which is already out of range
+	size := num * float64(multiplier)
+	if size >= math.MaxInt64 {
+		return -1, strconv.ErrRange
+	}
+
+	return int64(size), nil
+}
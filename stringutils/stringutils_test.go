@@ -127,3 +127,55 @@ type args struct {
 		})
 	}
 }
+
+func TestToByteSize(t *testing.T) {
+	var tests = []struct {
+		name       string
+		givenStr   string
+		expectSize int64
+		expectErr  bool
+	}{
+		{
+			name:       "ok: bytes",
+			givenStr:   "512",
+			expectSize: 512,
+		},
+		{
+			name:       "ok: decimal unit",
+			givenStr:   "10MB",
+			expectSize: 10 * 1000 * 1000,
+		},
+		{
+			name:       "ok: binary unit with space and lower case",
+			givenStr:   "1.5 kib",
+			expectSize: 1536,
+		},
+		{
+			name:      "error: unknown unit",
+			givenStr:  "10XB",
+			expectErr: true,
+		},
+		{
+			name:      "error: missing number",
+			givenStr:  "MB",
+			expectErr: true,
+		},
+		{
+			name:      "error: exceeds the largest size",
+			givenStr:  "10000000TB",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size, err := ToByteSize(tt.givenStr)
+			if err != nil {
+				assert.True(t, tt.expectErr)
+			} else {
+				assert.False(t, tt.expectErr)
+				assert.Equal(t, tt.expectSize, size)
+			}
+		})
+	}
+}
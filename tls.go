@@ -0,0 +1,250 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fastecho
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ingka-group/fastecho/env"
+	"github.com/ingka-group/fastecho/errs"
+	"github.com/ingka-group/fastecho/stringutils"
+)
+
+const (
+	tlsCertFile     = "TLS_CERT_FILE"
+	tlsKeyFile      = "TLS_KEY_FILE"
+	tlsClientCAFile = "TLS_CLIENT_CA_FILE"
+	tlsMinVersion   = "TLS_MIN_VERSION"
+
+	// certReloadInterval defines how often the certificate files are checked for changes.
+	certReloadInterval = 10 * time.Second
+)
+
+// newTLSEnvs returns the environment variables for serving over TLS. TLS is enabled when a certificate
+// is provided.
+func newTLSEnvs() env.Map {
+	return env.Map{
+		tlsCertFile: {
+			Description: "Path to the PEM certificate of the server. Enables TLS",
+			Optional:    true,
+		},
+		tlsKeyFile: {
+			Description: "Path to the PEM private key of the server",
+			Optional:    true,
+		},
+		tlsClientCAFile: {
+			Description: "Path to the PEM CA clients must present a certificate signed by. Enables mTLS",
+			Optional:    true,
+		},
+		tlsMinVersion: {
+			Description:  "Minimum TLS version accepted by the server",
+			DefaultValue: "1.2",
+			OneOf:        []string{"1.0", "1.1", "1.2", "1.3"},
+		},
+	}
+}
+
+var (
+	tlsVersions = map[string]uint16{
+		"1.0": tls.VersionTLS10,
+		"1.1": tls.VersionTLS11,
+		"1.2": tls.VersionTLS12,
+		"1.3": tls.VersionTLS13,
+	}
+)
+
+// newTLSConfig creates the TLS configuration of the server based on the environment variables.
+// It returns nil if no certificate is provided, i.e. the server is served over plain HTTP.
+func newTLSConfig(envs env.Map, logger *zap.Logger) (*tls.Config, error) {
+	certFile := envs[tlsCertFile].Value
+	keyFile := envs[tlsKeyFile].Value
+	caFile := envs[tlsClientCAFile].Value
+
+	if stringutils.IsEmpty(certFile) && stringutils.IsEmpty(keyFile) {
+		if !stringutils.IsEmpty(caFile) {
+			return nil, errs.New(fmt.Sprintf("variable `%s` requires `%s` and `%s` to be set", tlsClientCAFile, tlsCertFile, tlsKeyFile))
+		}
+		return nil, nil
+	}
+
+	if stringutils.IsEmpty(certFile) || stringutils.IsEmpty(keyFile) {
+		return nil, errs.New(fmt.Sprintf("variables `%s` and `%s` must be set together", tlsCertFile, tlsKeyFile))
+	}
+
+	reloader := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		caFile:   caFile,
+		logger:   logger,
+	}
+
+	err := reloader.load()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{
+		MinVersion:     tlsVersions[envs[tlsMinVersion].Value],
+		GetCertificate: reloader.getCertificate,
+		// HTTP/2 is only negotiated if it's advertised by the listener
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+
+	if !stringutils.IsEmpty(caFile) {
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		cfg.GetConfigForClient = reloader.getConfigForClient(cfg)
+	}
+
+	return cfg, nil
+}
+
+// certReloader serves the server certificate and the client CAs, and reloads them when their files change on disk.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+	logger   *zap.Logger
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  map[string]time.Time
+	checkedAt time.Time
+}
+
+// load reads the certificate, the key and the client CA from their files.
+func (r *certReloader) load() error {
+	modTimes, err := r.statFiles()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	var clientCAs *x509.CertPool
+	if !stringutils.IsEmpty(r.caFile) {
+		pem, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return err
+		}
+
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return errs.New(fmt.Sprintf("no valid certificates found in `%s`", r.caFile))
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	r.modTimes = modTimes
+	r.checkedAt = time.Now()
+
+	return nil
+}
+
+// statFiles returns the modification times of the certificate files.
+func (r *certReloader) statFiles() (map[string]time.Time, error) {
+	modTimes := make(map[string]time.Time)
+	for _, file := range []string{r.certFile, r.keyFile, r.caFile} {
+		if stringutils.IsEmpty(file) {
+			continue
+		}
+
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		modTimes[file] = info.ModTime()
+	}
+
+	return modTimes, nil
+}
+
+// reloadIfChanged reloads the certificate files if any of them changed since they were last loaded.
+// The files are checked at most once per certReloadInterval. If the reload fails, the previous
+// certificates are kept.
+func (r *certReloader) reloadIfChanged() {
+	r.mu.Lock()
+	if time.Since(r.checkedAt) < certReloadInterval {
+		r.mu.Unlock()
+		return
+	}
+	r.checkedAt = time.Now()
+	previous := r.modTimes
+	r.mu.Unlock()
+
+	modTimes, err := r.statFiles()
+	if err != nil {
+		r.logger.Error("Failed to check the TLS certificate files", zap.Error(err))
+		return
+	}
+
+	changed := false
+	for file, modTime := range modTimes {
+		if !modTime.Equal(previous[file]) {
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+
+	err = r.load()
+	if err != nil {
+		r.logger.Error("Failed to reload the TLS certificates, keeping the previous ones", zap.Error(err))
+		return
+	}
+
+	r.logger.Info("Reloaded the TLS certificates")
+}
+
+// getCertificate returns the current server certificate.
+func (r *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.reloadIfChanged()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cert, nil
+}
+
+// getConfigForClient returns a copy of the given configuration that uses the current client CAs.
+func (r *certReloader) getConfigForClient(base *tls.Config) func(*tls.ClientHelloInfo) (*tls.Config, error) {
+	return func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		r.reloadIfChanged()
+
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+
+		cfg := base.Clone()
+		cfg.GetConfigForClient = nil
+		cfg.ClientCAs = r.clientCAs
+
+		return cfg, nil
+	}
+}
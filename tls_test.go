@@ -0,0 +1,268 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fastecho
+
+import (
+	gocontext "context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/ingka-group/fastecho/context"
+	"github.com/ingka-group/fastecho/env"
+	"github.com/ingka-group/fastecho/router"
+)
+
+// testCert is a certificate generated for the tests, with its key.
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+// newTestCert generates a certificate with the given common name, signed by the given parent or self-signed.
+func newTestCert(t *testing.T, commonName string, parent *testCert) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+// write writes the certificate and its key as PEM files to the directory, and returns their paths.
+func (c *testCert) write(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+
+	keyDer, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600))
+
+	return certFile, keyFile
+}
+
+// tlsClient returns a HTTP client trusting the CA, which presents the given certificate, if any.
+func (c *testCert) tlsClient(clientCert *testCert) *http.Client {
+	roots := x509.NewCertPool()
+	roots.AddCert(c.cert)
+
+	cfg := &tls.Config{RootCAs: roots}
+	if clientCert != nil {
+		cfg.Certificates = []tls.Certificate{{Certificate: [][]byte{clientCert.der}, PrivateKey: clientCert.key}}
+	}
+
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: cfg, ForceAttemptHTTP2: true}}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "ca", nil)
+	caFile, _ := ca.write(t, dir, "ca")
+	certFile, keyFile := newTestCert(t, "server", ca).write(t, dir, "server")
+
+	tests := []struct {
+		name             string
+		certFile         string
+		keyFile          string
+		caFile           string
+		minVersion       string
+		expectNil        bool
+		expectMinVersion uint16
+		expectClientAuth tls.ClientAuthType
+		expectErr        bool
+	}{
+		{
+			name:      "ok: plain HTTP without certificate",
+			expectNil: true,
+		},
+		{
+			name:             "ok: TLS",
+			certFile:         certFile,
+			keyFile:          keyFile,
+			minVersion:       "1.2",
+			expectMinVersion: tls.VersionTLS12,
+			expectClientAuth: tls.NoClientCert,
+		},
+		{
+			name:             "ok: mTLS with TLS 1.3",
+			certFile:         certFile,
+			keyFile:          keyFile,
+			caFile:           caFile,
+			minVersion:       "1.3",
+			expectMinVersion: tls.VersionTLS13,
+			expectClientAuth: tls.RequireAndVerifyClientCert,
+		},
+		{
+			name:      "error: certificate without key",
+			certFile:  certFile,
+			expectErr: true,
+		},
+		{
+			name:      "error: client CA without certificate",
+			caFile:    caFile,
+			expectErr: true,
+		},
+		{
+			name:      "error: missing certificate file",
+			certFile:  filepath.Join(dir, "missing.crt"),
+			keyFile:   keyFile,
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			envs := env.Map{
+				tlsCertFile:     {Value: tt.certFile},
+				tlsKeyFile:      {Value: tt.keyFile},
+				tlsClientCAFile: {Value: tt.caFile},
+				tlsMinVersion:   {Value: tt.minVersion},
+			}
+
+			cfg, err := newTLSConfig(envs, zap.NewNop())
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			if tt.expectNil {
+				assert.Nil(t, cfg)
+				return
+			}
+			assert.Equal(t, tt.expectMinVersion, cfg.MinVersion)
+			assert.Equal(t, tt.expectClientAuth, cfg.ClientAuth)
+			assert.Equal(t, []string{"h2", "http/1.1"}, cfg.NextProtos)
+		})
+	}
+}
+
+func TestCertReloader_reloadIfChanged(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "ca", nil)
+	certFile, keyFile := newTestCert(t, "first", ca).write(t, dir, "server")
+
+	r := &certReloader{certFile: certFile, keyFile: keyFile, logger: zap.NewNop()}
+	require.NoError(t, r.load())
+
+	cert, err := r.getCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "first", cert.Leaf.Subject.CommonName)
+
+	// rotate the certificate, and let the reloader check the files again
+	newTestCert(t, "second", ca).write(t, dir, "server")
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+	r.checkedAt = time.Time{}
+
+	cert, err = r.getCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "second", cert.Leaf.Subject.CommonName)
+
+	// a broken rotation keeps the previous certificate
+	require.NoError(t, os.WriteFile(keyFile, []byte("broken"), 0o600))
+	r.checkedAt = time.Time{}
+
+	cert, err = r.getCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "second", cert.Leaf.Subject.CommonName)
+}
+
+func TestFastEcho_Start_mTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "ca", nil)
+	caFile, _ := ca.write(t, dir, "ca")
+	certFile, keyFile := newTestCert(t, "server", ca).write(t, dir, "server")
+	client := newTestCert(t, "client", ca)
+
+	t.Setenv(tlsCertFile, certFile)
+	t.Setenv(tlsKeyFile, keyFile)
+	t.Setenv(tlsClientCAFile, caFile)
+
+	fe := newTestFastEcho(t, &Config{
+		Routes: func(e *echo.Echo, _ *router.Router) error {
+			e.GET("/whoami", func(c echo.Context) error {
+				return c.String(http.StatusOK, context.GetServiceContext[any](c).ClientSubject)
+			})
+			return nil
+		},
+	})
+
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	defer cancel()
+	require.NoError(t, fe.Start(ctx))
+	url := "https://" + fe.Addr().String() + "/whoami"
+
+	resp, err := ca.tlsClient(client).Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body := make([]byte, 64)
+	n, _ := resp.Body.Read(body)
+	assert.Equal(t, "CN=client", string(body[:n]))
+	assert.Equal(t, 2, resp.ProtoMajor)
+
+	// clients without a certificate are rejected
+	_, err = ca.tlsClient(nil).Get(url)
+	assert.Error(t, err)
+}
@@ -42,13 +42,19 @@ func newTracer(serviceName string) (*sdktrace.TracerProvider, *oteltrace.Tracer,
 		)),
 	)
 
-	otel.SetTracerProvider(tp)
-	otel.SetTextMapPropagator(
-		propagation.NewCompositeTextMapPropagator(
-			propagation.TraceContext{}, propagation.Baggage{},
-		),
-	)
-
 	tracer := tp.Tracer(serviceName)
 	return tp, &tracer, nil
 }
+
+// newPropagator creates the propagator of the trace context and baggage of the requests.
+func newPropagator() propagation.TextMapPropagator {
+	return propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	)
+}
+
+// setGlobalTracer registers the tracer provider and the propagator as the OpenTelemetry globals.
+func setGlobalTracer(tp *sdktrace.TracerProvider, propagator propagation.TextMapPropagator) {
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagator)
+}
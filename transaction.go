@@ -0,0 +1,139 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fastecho
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+
+	"github.com/ingka-group/fastecho/context"
+)
+
+// txMiddleware runs each request in a transaction, which is committed when the response is successful and
+// rolled back when the handler fails, panics or responds with an error.
+func (s *server) txMiddleware(opts TransactionOpts) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if s.Router.SkipList.Skip(c) || (opts.MutatingOnly && !isMutating(c.Request().Method)) {
+				return next(c)
+			}
+
+			req := c.Request()
+			tx := opts.DB.WithContext(req.Context()).Begin()
+			if tx.Error != nil {
+				return tx.Error
+			}
+			c.SetRequest(req.WithContext(context.WithTx(req.Context(), tx)))
+
+			var done bool
+			commit := func() error {
+				done = true
+				return tx.Commit().Error
+			}
+			rollback := func() {
+				done = true
+				if err := tx.Rollback().Error; err != nil {
+					s.Logger.Warn("Failed to roll back the transaction", zap.Error(err))
+				}
+			}
+
+			defer func() {
+				if r := recover(); r != nil {
+					if !done {
+						rollback()
+					}
+					panic(r)
+				}
+			}()
+
+			// The transaction ends before the status of the response is written, so that a response isn't
+			// successful unless its transaction is committed. Otherwise, the response of the handler is
+			// replaced with an internal server error.
+			c.Response().Before(func() {
+				if done {
+					return
+				}
+
+				res := c.Response()
+				if !isSuccess(res.Status) {
+					rollback()
+					return
+				}
+
+				if err := commit(); err != nil {
+					s.Logger.Error("Failed to commit the transaction", zap.Error(err))
+					res.Status = http.StatusInternalServerError
+					res.Writer = &failedCommitWriter{ResponseWriter: res.Writer}
+				}
+			})
+
+			err := next(c)
+			if done {
+				return err
+			}
+
+			// The response isn't written yet, e.g. the handler returned an error which Echo responds with
+			if err != nil || !isSuccess(c.Response().Status) {
+				rollback()
+				return err
+			}
+
+			return commit()
+		}
+	}
+}
+
+// failedCommitWriter responds with an internal server error once the transaction of the request failed to commit,
+// like Echo does when a handler fails. The body written by the handler is discarded.
+type failedCommitWriter struct {
+	http.ResponseWriter
+}
+
+// WriteHeader writes the internal server error, whatever the status.
+func (w *failedCommitWriter) WriteHeader(int) {
+	w.Header().Del(echo.HeaderContentLength)
+	w.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	w.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+
+	_ = json.NewEncoder(w.ResponseWriter).Encode(echo.Map{"message": http.StatusText(http.StatusInternalServerError)})
+}
+
+// Write discards the body written by the handler.
+func (w *failedCommitWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+// Unwrap returns the original writer, e.g. to flush the response.
+func (w *failedCommitWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// isMutating reports whether the method of a request may change data.
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// isSuccess reports whether the status of a response is 2xx.
+func isSuccess(status int) bool {
+	return status >= http.StatusOK && status < http.StatusMultipleChoices
+}
@@ -0,0 +1,200 @@
+// Copyright © 2024 Ingka Holding B.V. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	  http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fastecho
+
+import (
+	gocontext "context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/ingka-group/fastecho/context"
+	"github.com/ingka-group/fastecho/errs"
+	"github.com/ingka-group/fastecho/router"
+)
+
+// txRecorder is a database driver which records how the transactions end.
+type txRecorder struct {
+	mu         sync.Mutex
+	events     []string
+	failCommit bool
+}
+
+func (r *txRecorder) record(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *txRecorder) Open(string) (driver.Conn, error) {
+	return &txRecorderConn{r: r}, nil
+}
+
+// txRecorderConn is a connection of the txRecorder, which is its own transaction.
+type txRecorderConn struct {
+	r *txRecorder
+}
+
+func (c *txRecorderConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *txRecorderConn) Close() error {
+	return nil
+}
+
+func (c *txRecorderConn) Begin() (driver.Tx, error) {
+	return c, nil
+}
+
+func (c *txRecorderConn) Commit() error {
+	c.r.record("commit")
+	if c.r.failCommit {
+		return errors.New("serialization failure")
+	}
+	return nil
+}
+
+func (c *txRecorderConn) Rollback() error {
+	c.r.record("rollback")
+	return nil
+}
+
+func TestServer_txMiddleware(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		mutatingOnly bool
+		failCommit   bool
+		handler      echo.HandlerFunc
+		wantStatus   int
+		wantBody     string
+		wantEvents   []string
+	}{
+		{
+			name:       "ok: successful response commits",
+			method:     http.MethodPost,
+			handler:    func(c echo.Context) error { return c.String(http.StatusCreated, "created") },
+			wantStatus: http.StatusCreated,
+			wantEvents: []string{"commit"},
+		},
+		{
+			name:       "ok: empty response commits",
+			method:     http.MethodDelete,
+			handler:    func(c echo.Context) error { return nil },
+			wantStatus: http.StatusOK,
+			wantEvents: []string{"commit"},
+		},
+		{
+			name:       "ok: error response rolls back",
+			method:     http.MethodPost,
+			handler:    func(c echo.Context) error { return c.String(http.StatusConflict, "conflict") },
+			wantStatus: http.StatusConflict,
+			wantEvents: []string{"rollback"},
+		},
+		{
+			name:       "ok: returned error rolls back",
+			method:     http.MethodPost,
+			handler:    func(c echo.Context) error { return errs.New("failed", errs.NotFound) },
+			wantStatus: http.StatusInternalServerError,
+			wantEvents: []string{"rollback"},
+		},
+		{
+			name:       "ok: panic rolls back",
+			method:     http.MethodPost,
+			handler:    func(c echo.Context) error { panic("boom") },
+			wantStatus: http.StatusInternalServerError,
+			wantEvents: []string{"rollback"},
+		},
+		{
+			name:       "error: failed commit replaces the response with an internal server error",
+			method:     http.MethodPost,
+			failCommit: true,
+			handler:    func(c echo.Context) error { return c.JSON(http.StatusCreated, echo.Map{"id": 1}) },
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   `{"message":"Internal Server Error"}`,
+			wantEvents: []string{"commit"},
+		},
+		{
+			name:       "error: failed commit of an empty response responds with an internal server error",
+			method:     http.MethodDelete,
+			failCommit: true,
+			handler:    func(c echo.Context) error { return nil },
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   `{"message":"Internal Server Error"}`,
+			wantEvents: []string{"commit"},
+		},
+		{
+			name:         "ok: read request without transaction",
+			method:       http.MethodGet,
+			mutatingOnly: true,
+			handler:      func(c echo.Context) error { return c.String(http.StatusOK, "ok") },
+			wantStatus:   http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &txRecorder{failCommit: tt.failCommit}
+			driverName := "txrecorder-" + t.Name()
+			sql.Register(driverName, recorder)
+
+			sqlDb, err := sql.Open(driverName, "")
+			require.NoError(t, err)
+			db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDb}), &gorm.Config{
+				DisableAutomaticPing: true,
+			})
+			require.NoError(t, err)
+
+			s := &server{
+				Router: &router.Router{SkipList: router.NewSkipList()},
+				Logger: zap.NewNop(),
+			}
+
+			e := echo.New()
+			e.Use(middleware.Recover())
+			e.Use(s.txMiddleware(TransactionOpts{DB: db, MutatingOnly: tt.mutatingOnly}))
+			e.Any("/orders", func(c echo.Context) error {
+				_, inTx := context.GetTx(c.Request().Context(), db).Statement.ConnPool.(*sql.Tx)
+				assert.Equal(t, len(tt.wantEvents) > 0, inTx)
+
+				return tt.handler(c)
+			})
+
+			req := httptest.NewRequestWithContext(gocontext.Background(), tt.method, "/orders", nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantBody != "" {
+				assert.JSONEq(t, tt.wantBody, rec.Body.String())
+				assert.Equal(t, echo.MIMEApplicationJSON, rec.Header().Get(echo.HeaderContentType))
+			}
+			assert.Equal(t, tt.wantEvents, recorder.events)
+		})
+	}
+}